@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+func (cfg *ApiConfig) HandleSetUserRole(out http.ResponseWriter, req *http.Request) {
+	type roleBody struct {
+		Role string `json:"role"`
+	}
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "It's not valid user ID")
+		return
+	}
+	parsedReqBody := roleBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if !auth.IsValidRole(parsedReqBody.Role) {
+		RespondWithError(out, 400, "Role must be user, moderator or admin")
+		return
+	}
+	// Admins can't demote themselves, so there is always someone left who
+	// can hand out roles.
+	if userID == requestUser(req).ID {
+		RespondWithError(out, 400, "You can't change your own role")
+		return
+	}
+
+	updated, err := cfg.DB_Config.Queries.SetUserRole(context.Background(), database.SetUserRoleParams{Role: parsedReqBody.Role, ID: userID})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if updated == 0 {
+		RespondWithError(out, 404, "User does not exist")
+		return
+	}
+	usr, err := cfg.DB_Config.Queries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	userBytes, _ := json.Marshal(RegisterFromDatabaseUser(usr))
+	RespondWithJSON(out, 200, userBytes)
+}
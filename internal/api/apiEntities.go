@@ -1,6 +1,7 @@
 package api
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,49 +9,335 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `json:"id"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
-	Email        string    `json:"email"`
-	Token        string    `json:"token"`
-	Refreshtoken string    `json:"refresh_token"`
-	IsChirpyRed  bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	Token         string    `json:"token"`
+	Refreshtoken  string    `json:"refresh_token"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	Handle        string    `json:"handle"`
 }
 
 type RegisterResponse struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	EmailVerified bool      `json:"email_verified"`
+	Role          string    `json:"role"`
+	Handle        string    `json:"handle"`
 }
 
 func RegisterFromDatabaseUser(dbUser database.User) RegisterResponse {
 	return RegisterResponse{
-		ID:          dbUser.ID,
-		CreatedAt:   dbUser.CreatedAt,
-		UpdatedAt:   dbUser.UpdatedAt,
-		Email:       dbUser.Email,
-		IsChirpyRed: dbUser.IsChirpyRed,
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		IsChirpyRed:   dbUser.IsChirpyRed,
+		EmailVerified: dbUser.EmailVerifiedAt.Valid,
+		Role:          dbUser.Role,
+		Handle:        dbUser.Handle,
 	}
 }
 
 func FromDatabaseUser(dbUser database.User, token string, refreshToken string) User {
 	return User{
-		ID:           dbUser.ID,
-		CreatedAt:    dbUser.CreatedAt,
-		UpdatedAt:    dbUser.UpdatedAt,
-		Email:        dbUser.Email,
-		Token:        token,
-		Refreshtoken: refreshToken,
-		IsChirpyRed:  dbUser.IsChirpyRed,
+		ID:            dbUser.ID,
+		CreatedAt:     dbUser.CreatedAt,
+		UpdatedAt:     dbUser.UpdatedAt,
+		Email:         dbUser.Email,
+		Token:         token,
+		Refreshtoken:  refreshToken,
+		IsChirpyRed:   dbUser.IsChirpyRed,
+		EmailVerified: dbUser.EmailVerifiedAt.Valid,
+		Role:          dbUser.Role,
+		Handle:        dbUser.Handle,
 	}
 }
 
 type Chirp struct {
-	ID        uuid.UUID `json:"id"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
-	Body      string    `json:"body"`
-	UserID    uuid.UUID `json:"user_id"`
+	ID        uuid.UUID     `json:"id"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+	Body      string        `json:"body"`
+	UserID    uuid.UUID     `json:"user_id"`
+	ParentID  uuid.NullUUID `json:"parent_id"`
+	Author    ChirpAuthor   `json:"author"`
+	Edited    bool          `json:"edited"`
+	Deleted   bool          `json:"deleted"`
+	Hidden    bool          `json:"hidden"`
+	LikeCount int64         `json:"like_count"`
+	LikedByMe bool          `json:"liked_by_me"`
+}
+
+func FromDatabaseChirp(dbChirp database.Chirp) Chirp {
+	return Chirp{
+		ID:        dbChirp.ID,
+		CreatedAt: dbChirp.CreatedAt,
+		UpdatedAt: dbChirp.UpdatedAt,
+		Body:      dbChirp.Body,
+		UserID:    dbChirp.UserID,
+		ParentID:  dbChirp.ParentID,
+		Edited:    dbChirp.EditedAt.Valid,
+		Deleted:   dbChirp.DeletedAt.Valid,
+		Hidden:    dbChirp.HiddenAt.Valid,
+	}
+}
+
+// ChirpAuthor is the public part of the profile of a chirp's author.
+type ChirpAuthor struct {
+	Handle      string `json:"handle"`
+	DisplayName string `json:"display_name"`
+}
+
+type ChirpPage struct {
+	Chirps     []Chirp `json:"chirps"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+}
+
+type ChirpSearchResult struct {
+	Chirp
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+type ChirpSearchPage struct {
+	Results    []ChirpSearchResult `json:"results"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID `json:"id"`
+	ChirpID    uuid.UUID `json:"chirp_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+func FromDatabaseChirpRevision(dbRevision database.ChirpRevision) ChirpRevision {
+	return ChirpRevision{
+		ID:         dbRevision.ID,
+		ChirpID:    dbRevision.ChirpID,
+		Body:       dbRevision.Body,
+		CreatedAt:  dbRevision.CreatedAt,
+		ReplacedAt: dbRevision.ReplacedAt,
+	}
+}
+
+type Follow struct {
+	UserID     uuid.UUID `json:"user_id"`
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+type FollowPage struct {
+	Users      []Follow `json:"users"`
+	NextCursor string   `json:"next_cursor,omitempty"`
+}
+
+type Session struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+type MFAChallenge struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+type MFASetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type MFARecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID       `json:"id"`
+	EventID     string          `json:"event_id"`
+	Event       string          `json:"event"`
+	Payload     json.RawMessage `json:"payload"`
+	ReceivedAt  time.Time       `json:"received_at"`
+	ProcessedAt *time.Time      `json:"processed_at"`
+	Attempts    int32           `json:"attempts"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+func FromDatabaseWebhookEvent(dbEvent database.WebhookEvent) WebhookEvent {
+	event := WebhookEvent{
+		ID:         dbEvent.ID,
+		EventID:    dbEvent.EventID,
+		Event:      dbEvent.Event,
+		Payload:    dbEvent.Payload,
+		ReceivedAt: dbEvent.ReceivedAt,
+		Attempts:   dbEvent.Attempts,
+		LastError:  dbEvent.LastError.String,
+	}
+	if dbEvent.ProcessedAt.Valid {
+		event.ProcessedAt = &dbEvent.ProcessedAt.Time
+	}
+	return event
+}
+
+type WebhookEventPage struct {
+	Events     []WebhookEvent `json:"events"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type Subscription struct {
+	Plan        string     `json:"plan,omitempty"`
+	Status      string     `json:"status"`
+	IsChirpyRed bool       `json:"is_chirpy_red"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	EndedAt     *time.Time `json:"ended_at,omitempty"`
+}
+
+func FromDatabaseSubscription(dbSubscription database.Subscription, isChirpyRed bool) Subscription {
+	subscription := Subscription{
+		Plan:        dbSubscription.Plan,
+		Status:      dbSubscription.Status,
+		IsChirpyRed: isChirpyRed,
+		StartedAt:   &dbSubscription.StartedAt,
+	}
+	if dbSubscription.ExpiresAt.Valid {
+		subscription.ExpiresAt = &dbSubscription.ExpiresAt.Time
+	}
+	if dbSubscription.EndedAt.Valid {
+		subscription.EndedAt = &dbSubscription.EndedAt.Time
+	}
+	return subscription
+}
+
+type CensoredWord struct {
+	ID          uuid.UUID `json:"id"`
+	Pattern     string    `json:"pattern"`
+	Strategy    string    `json:"strategy"`
+	Replacement string    `json:"replacement,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func FromDatabaseCensoredWord(dbWord database.CensoredWord) CensoredWord {
+	return CensoredWord{
+		ID:          dbWord.ID,
+		Pattern:     dbWord.Pattern,
+		Strategy:    dbWord.Strategy,
+		Replacement: dbWord.Replacement,
+		CreatedAt:   dbWord.CreatedAt,
+	}
+}
+
+type Report struct {
+	ID         uuid.UUID     `json:"id"`
+	ChirpID    uuid.UUID     `json:"chirp_id"`
+	ReporterID uuid.UUID     `json:"reporter_id"`
+	Reason     string        `json:"reason"`
+	Details    string        `json:"details"`
+	Status     string        `json:"status"`
+	CreatedAt  time.Time     `json:"created_at"`
+	ResolvedAt *time.Time    `json:"resolved_at"`
+	ResolvedBy uuid.NullUUID `json:"resolved_by"`
+}
+
+func FromDatabaseReport(dbReport database.Report) Report {
+	report := Report{
+		ID:         dbReport.ID,
+		ChirpID:    dbReport.ChirpID,
+		ReporterID: dbReport.ReporterID,
+		Reason:     dbReport.Reason,
+		Details:    dbReport.Details,
+		Status:     dbReport.Status,
+		CreatedAt:  dbReport.CreatedAt,
+		ResolvedBy: dbReport.ResolvedBy,
+	}
+	if dbReport.ResolvedAt.Valid {
+		report.ResolvedAt = &dbReport.ResolvedAt.Time
+	}
+	return report
+}
+
+// QueuedReport is a report together with the chirp it is about, as shown to
+// moderators.
+type QueuedReport struct {
+	Report
+	Chirp Chirp `json:"chirp"`
+}
+
+type ReportPage struct {
+	Reports    []QueuedReport `json:"reports"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+}
+
+type ModerationAction struct {
+	ID             uuid.UUID     `json:"id"`
+	ModeratorID    uuid.UUID     `json:"moderator_id"`
+	Action         string        `json:"action"`
+	ChirpID        uuid.NullUUID `json:"chirp_id"`
+	UserID         uuid.NullUUID `json:"user_id"`
+	ReportID       uuid.NullUUID `json:"report_id"`
+	Reason         string        `json:"reason"`
+	SuspendedUntil *time.Time    `json:"suspended_until,omitempty"`
+	CreatedAt      time.Time     `json:"created_at"`
+}
+
+func FromDatabaseModerationAction(dbAction database.ModerationAction) ModerationAction {
+	action := ModerationAction{
+		ID:          dbAction.ID,
+		ModeratorID: dbAction.ModeratorID,
+		Action:      dbAction.Action,
+		ChirpID:     dbAction.ChirpID,
+		UserID:      dbAction.UserID,
+		ReportID:    dbAction.ReportID,
+		Reason:      dbAction.Reason,
+		CreatedAt:   dbAction.CreatedAt,
+	}
+	if dbAction.SuspendedUntil.Valid {
+		action.SuspendedUntil = &dbAction.SuspendedUntil.Time
+	}
+	return action
+}
+
+type ModerationActionPage struct {
+	Actions    []ModerationAction `json:"actions"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+// Profile is what anyone can see about a user. It must never include the
+// email address.
+type Profile struct {
+	ID             uuid.UUID `json:"id"`
+	Handle         string    `json:"handle"`
+	DisplayName    string    `json:"display_name"`
+	Bio            string    `json:"bio"`
+	AvatarURL      string    `json:"avatar_url"`
+	IsChirpyRed    bool      `json:"is_chirpy_red"`
+	CreatedAt      time.Time `json:"created_at"`
+	ChirpCount     int64     `json:"chirp_count"`
+	FollowerCount  int64     `json:"follower_count"`
+	FollowingCount int64     `json:"following_count"`
+}
+
+func FromDatabaseProfile(dbUser database.User, stats database.GetUserProfileStatsRow) Profile {
+	return Profile{
+		ID:             dbUser.ID,
+		Handle:         dbUser.Handle,
+		DisplayName:    dbUser.DisplayName,
+		Bio:            dbUser.Bio,
+		AvatarURL:      dbUser.AvatarUrl,
+		IsChirpyRed:    dbUser.IsChirpyRed,
+		CreatedAt:      dbUser.CreatedAt,
+		ChirpCount:     stats.ChirpCount,
+		FollowerCount:  stats.FollowerCount,
+		FollowingCount: stats.FollowingCount,
+	}
 }
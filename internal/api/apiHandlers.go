@@ -6,22 +6,48 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"html"
+	"log"
 	"net/http"
-	"slices"
+	"net/netip"
 	"strings"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/censor"
 	"github.com/widua/go-http-server/internal/database"
+	"github.com/widua/go-http-server/internal/entitlements"
+	"github.com/widua/go-http-server/internal/mail"
+	"github.com/widua/go-http-server/internal/ratelimit"
 )
 
 type ApiConfig struct {
 	FileServerHits atomic.Int32
-	JWT_Secret     string
+	JWT_Keys       *auth.KeySet
 	POLKA_KEY      string
 	DB_Config      *database.DatabaseConfig
+	Mailer         mail.Mailer
+	RateLimiter    ratelimit.Store
+	// BaseURL is where this server is reachable from outside, used for links
+	// in emails.
+	BaseURL string
+	// Platform is "dev" on development machines, where destructive admin
+	// endpoints like reset are allowed.
+	Platform string
+	// RequireVerifiedEmail stops accounts without a verified email address
+	// from posting chirps.
+	RequireVerifiedEmail bool
+	// Plans holds the limits of free and Chirpy Red accounts.
+	Plans entitlements.Plans
+	// Censor masks profanity in chirps. Its runtime word list is managed
+	// through the admin API.
+	Censor *censor.Censor
+	// TrustedProxies are the load balancers and reverse proxies allowed to
+	// tell the client's address in X-Forwarded-For.
+	TrustedProxies []netip.Prefix
 }
 
 func HandleFileserver() http.Handler {
@@ -42,43 +68,52 @@ func (cfg *ApiConfig) HandleMetrics(out http.ResponseWriter, req *http.Request)
 }
 
 func (cfg *ApiConfig) HandleReset(out http.ResponseWriter, req *http.Request) {
+	if cfg.Platform != "dev" {
+		RespondWithError(out, 403, "Reset is only allowed in dev environment")
+		return
+	}
 	cfg.FileServerHits.Store(0)
 	cfg.DB_Config.Queries.ResetUsers(context.Background())
 	cfg.DB_Config.Queries.ResetChirps(context.Background())
+	cfg.DB_Config.Queries.ResetLoginFailures(context.Background())
 	RespondOk(out)
 }
 
-func (cfg *ApiConfig) ValidateChirp(body string) (string, error) {
-
-	if len(body) > 140 {
-		return "", errors.New("Chirp is too long")
-	}
-	censoredChirp := ChirpClearer(body)
-	return censoredChirp, nil
-}
-
-func ChirpClearer(body string) string {
-	var profaneWords []string = []string{"kerfuffle", "sharbert", "fornax"}
-
-	splittedBody := strings.Split(body, " ")
-
-	for ix, word := range splittedBody {
-		if slices.Contains(profaneWords, strings.ToLower(word)) {
-			splittedBody[ix] = "****"
+// ValidateChirp returns the body to store for a chirp, or a *ValidationError
+// when the plan doesn't allow it.
+func (cfg *ApiConfig) ValidateChirp(body string, plan entitlements.Plan) (string, error) {
+	body = normalizeChirp(body)
+	if err := checkChirpText(body, plan.MaxChirpLength); err != nil {
+		return "", err
+	}
+	censoredChirp := cfg.Censor.Clean(body)
+	// Replacements can be longer than the words they replace, and the
+	// stored chirp has to fit the plan too.
+	if length := utf8.RuneCountInString(censoredChirp); length > plan.MaxChirpLength {
+		return "", &ValidationError{
+			Code:    validationTooLong,
+			Message: fmt.Sprintf("Chirp is too long once censored, %d characters of %d allowed", length, plan.MaxChirpLength),
+			Limit:   plan.MaxChirpLength,
+			Length:  length,
 		}
 	}
-	censoredBody := strings.Join(splittedBody, " ")
-
-	return censoredBody
+	return censoredChirp, nil
 }
 
 func (cfg *ApiConfig) HandleHealthz(out http.ResponseWriter, req *http.Request) {
 	RespondOk(out)
 }
+
+func (cfg *ApiConfig) HandleJWKS(out http.ResponseWriter, req *http.Request) {
+	jwks, _ := json.Marshal(cfg.JWT_Keys.JWKS())
+	out.Header().Set("Cache-Control", "public, max-age=300")
+	RespondWithJSON(out, 200, jwks)
+}
 func (cfg *ApiConfig) HandleCreateUser(out http.ResponseWriter, req *http.Request) {
 	type createUserBody struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Handle   string `json:"handle"`
 	}
 	parsedBody := createUserBody{}
 	decoder := json.NewDecoder(req.Body)
@@ -92,8 +127,49 @@ func (cfg *ApiConfig) HandleCreateUser(out http.ResponseWriter, req *http.Reques
 		RespondWithError(out, 400, "Invalid body")
 		return
 	}
+	if err := validateEmail(parsedBody.Email); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	// Without a handle, the user gets a generated one to change later.
+	handle := sql.NullString{}
+	if parsedBody.Handle != "" {
+		handle = sql.NullString{String: normalizeHandle(parsedBody.Handle), Valid: true}
+		if err := validateHandle(handle.String); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+	}
 	passwdHash, _ := auth.HashPassword(parsedBody.Password)
-	usr, err := cfg.DB_Config.Queries.CreateUser(context.Background(), database.CreateUserParams{Email: parsedBody.Email, HashedPassword: passwdHash})
+	var usr database.User
+	// Every attempt generates a new handle, so only a handle the user picked
+	// keeps colliding.
+	for attempt := 0; attempt < maxGeneratedHandleAttempts; attempt++ {
+		usr, err = cfg.DB_Config.Queries.CreateUser(context.Background(), database.CreateUserParams{Email: parsedBody.Email, HashedPassword: passwdHash, Handle: handle})
+		if handle.Valid || !database.IsUniqueViolation(err, handleIndex) {
+			break
+		}
+	}
+	if handle.Valid && database.IsUniqueViolation(err, handleIndex) {
+		RespondWithError(out, 409, "Handle is already taken")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, "Problem while creating User")
+		return
+	}
+	// On dev machines, the first user after a reset becomes admin, so the
+	// database can be reset again without editing it by hand.
+	if cfg.Platform == "dev" {
+		if promoted, err := cfg.DB_Config.Queries.PromoteIfNoAdmin(context.Background(), usr.ID); err == nil {
+			usr = promoted
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error while promoting %v to admin: %v", usr.ID, err)
+		}
+	}
+	if err := cfg.sendEmailVerification(usr); err != nil {
+		log.Printf("Error while creating email verification for %v: %v", usr.ID, err)
+	}
 
 	user := RegisterFromDatabaseUser(usr)
 	byteBody, err := json.Marshal(user)
@@ -107,7 +183,9 @@ func (cfg *ApiConfig) HandleCreateUser(out http.ResponseWriter, req *http.Reques
 }
 func (cfg *ApiConfig) HandleCreateChirp(out http.ResponseWriter, req *http.Request) {
 	type createChirpBody struct {
-		Body string `json:"body"`
+		Body      string     `json:"body"`
+		ParentID  *uuid.UUID `json:"parent_id"`
+		PublishAt *time.Time `json:"publish_at"`
 	}
 	parsedReqBody := createChirpBody{}
 	decoder := json.NewDecoder(req.Body)
@@ -121,20 +199,86 @@ func (cfg *ApiConfig) HandleCreateChirp(out http.ResponseWriter, req *http.Reque
 		RespondWithError(out, 400, "Invalid body")
 		return
 	}
-	token, err := auth.GetBearerToken(req.Header)
+	usr := requestUser(req)
+	if respondIfSuspended(out, usr) {
+		return
+	}
+	if cfg.RequireVerifiedEmail && !usr.EmailVerifiedAt.Valid {
+		RespondWithError(out, 403, "Email address is not verified")
+		return
+	}
+
+	plan := cfg.Plans.For(usr.IsChirpyRed)
+
+	chirpBody, err := cfg.ValidateChirp(parsedReqBody.Body, plan)
 	if err != nil {
-		RespondWithError(out, 401, err.Error())
+		respondWithValidationError(out, err)
+		return
+	}
+	if parsedReqBody.PublishAt != nil && !plan.CanScheduleChirps() {
+		RespondWithError(out, 403, "Scheduling chirps requires Chirpy Red")
 		return
 	}
-	tokenuuid, err := auth.ValidateJWT(token, cfg.JWT_Secret)
+	publishAt, err := validatePublishAt(parsedReqBody.PublishAt, plan, time.Now())
 	if err != nil {
-		RespondWithError(out, 401, err.Error())
+		RespondWithError(out, 400, err.Error())
 		return
 	}
 
-	chirp, err := cfg.DB_Config.Queries.CreateChirp(context.Background(), database.CreateChirpParams{Body: parsedReqBody.Body, UserID: tokenuuid})
-	mappedChirp := Chirp{ID: chirp.ID, CreatedAt: chirp.CreatedAt, UpdatedAt: chirp.UpdatedAt, Body: chirp.Body, UserID: chirp.UserID}
-	byteBody, err := json.Marshal(mappedChirp)
+	parentID := uuid.NullUUID{}
+	if parsedReqBody.ParentID != nil {
+		parent, err := cfg.DB_Config.Queries.GetVisibleChirpByID(context.Background(), *parsedReqBody.ParentID)
+		if err != nil || parent.DeletedAt.Valid {
+			RespondWithError(out, 404, "Parent chirp does not exist")
+			return
+		}
+		if cfg.respondIfBlocked(out, parent.UserID, usr.ID, "You can't reply to this user") {
+			return
+		}
+		parentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	if plan.DailyChirpQuota > 0 {
+		// Concurrent posts of the same user wait for each other here, so
+		// they can't all pass the quota check before any of them is stored.
+		if err := queries.LockUser(context.Background(), usr.ID); err != nil {
+			RespondWithError(out, 500, err.Error())
+			return
+		}
+		submitted, err := queries.CountChirpsSubmittedLastDay(context.Background(), usr.ID)
+		if err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+		if submitted >= int64(plan.DailyChirpQuota) {
+			RespondWithError(out, 429, "Daily chirp quota reached")
+			return
+		}
+	}
+
+	chirp, err := queries.CreateChirp(context.Background(), database.CreateChirpParams{PublishAt: publishAt, Body: chirpBody, UserID: usr.ID, ParentID: parentID})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	mappedChirps := []Chirp{FromDatabaseChirp(chirp)}
+	if err := cfg.addAuthors(mappedChirps); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	byteBody, err := json.Marshal(mappedChirps[0])
 	if err != nil {
 		RespondWithError(out, 400, err.Error())
 		return
@@ -145,38 +289,144 @@ func (cfg *ApiConfig) HandleCreateChirp(out http.ResponseWriter, req *http.Reque
 func (cfg *ApiConfig) HandleGetChirps(out http.ResponseWriter, req *http.Request) {
 	optionalAuthorQuery := req.URL.Query().Get("author_id")
 	optionalSortQuery := req.URL.Query().Get("sort")
+	if optionalSortQuery != "" && optionalSortQuery != "asc" && optionalSortQuery != "desc" {
+		RespondWithError(out, 400, "sort must be asc or desc")
+		return
+	}
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	descending := optionalSortQuery == "desc"
+	viewer := viewerID(req)
+
 	var chirps []database.Chirp
-	var err error
 	if optionalAuthorQuery != "" {
-		authorId, err := uuid.Parse(optionalAuthorQuery)
-		if err != nil {
-			RespondWithError(out, 400, err.Error())
+		authorId, parseErr := uuid.Parse(optionalAuthorQuery)
+		if parseErr != nil {
+			RespondWithError(out, 400, parseErr.Error())
 			return
 		}
-		chirps, err = cfg.DB_Config.Queries.GetChirpsByUserID(context.Background(), authorId)
+		if descending {
+			chirps, err = cfg.DB_Config.Queries.GetChirpsByUserIDDesc(context.Background(), database.GetChirpsByUserIDDescParams{UserID: authorId, ViewerID: viewer, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+		} else {
+			chirps, err = cfg.DB_Config.Queries.GetChirpsByUserID(context.Background(), database.GetChirpsByUserIDParams{UserID: authorId, ViewerID: viewer, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+		}
+	} else if descending {
+		chirps, err = cfg.DB_Config.Queries.GetAllChirpsDesc(context.Background(), database.GetAllChirpsDescParams{ViewerID: viewer, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
 	} else {
-		chirps, err = cfg.DB_Config.Queries.GetAllChirps(context.Background())
+		chirps, err = cfg.DB_Config.Queries.GetAllChirps(context.Background(), database.GetAllChirpsParams{ViewerID: viewer, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
 	}
 	if err != nil {
 		RespondWithError(out, 400, err.Error())
 		return
 	}
-	mappedChirps := make([]Chirp, len(chirps))
+	chirps, nextCursor := paginate(chirps, page, func(chirp database.Chirp) pageCursor {
+		return pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	})
 
+	mappedChirps := make([]Chirp, len(chirps))
 	for ix, chirp := range chirps {
-		mappedChirps[ix] = Chirp{ID: chirp.ID, CreatedAt: chirp.CreatedAt, UpdatedAt: chirp.UpdatedAt, Body: chirp.Body, UserID: chirp.UserID}
+		mappedChirps[ix] = FromDatabaseChirp(chirp)
 	}
-
-	if optionalSortQuery == "desc" {
-		slices.Reverse(mappedChirps)
+	if err := cfg.addLikes(mappedChirps, viewer); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := cfg.addAuthors(mappedChirps); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
 	}
 
-	mappedChirpsBytes, _ := json.Marshal(mappedChirps)
+	mappedChirpsBytes, _ := json.Marshal(ChirpPage{Chirps: mappedChirps, NextCursor: nextCursor})
 
 	RespondWithJSON(out, 200, mappedChirpsBytes)
 
 }
 
+// Matches in search snippets are delimited by these control characters,
+// which chirps can't contain, so the body can be escaped before the
+// delimiters become <mark> tags.
+const (
+	snippetMatchStart = "\x01"
+	snippetMatchEnd   = "\x02"
+)
+
+// highlightSnippet turns a snippet of SearchChirps into HTML where only the
+// <mark> tags around matches are markup.
+func highlightSnippet(snippet string) string {
+	return strings.NewReplacer(snippetMatchStart, "<mark>", snippetMatchEnd, "</mark>").Replace(html.EscapeString(snippet))
+}
+
+func (cfg *ApiConfig) HandleSearchChirps(out http.ResponseWriter, req *http.Request) {
+	searchQuery := strings.TrimSpace(req.URL.Query().Get("q"))
+	if searchQuery == "" {
+		RespondWithError(out, 400, "Missing search query")
+		return
+	}
+	page, err := parseRankedPageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	params := database.SearchChirpsParams{
+		Query:           searchQuery,
+		ViewerID:        viewerID(req),
+		CursorRank:      page.cursorRank(),
+		CursorCreatedAt: page.cursorCreatedAt(),
+		CursorID:        page.cursorID(),
+		PageSize:        page.queryLimit(),
+	}
+	if optionalAuthorQuery := req.URL.Query().Get("author_id"); optionalAuthorQuery != "" {
+		authorId, err := uuid.Parse(optionalAuthorQuery)
+		if err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+		params.AuthorID = uuid.NullUUID{UUID: authorId, Valid: true}
+	}
+	if params.CreatedAfter, err = parseTimeQuery(req, "since"); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if params.CreatedBefore, err = parseTimeQuery(req, "until"); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	results, err := cfg.DB_Config.Queries.SearchChirps(context.Background(), params)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	results, nextCursor := paginate(results, page, func(result database.SearchChirpsRow) pageCursor {
+		return pageCursor{CreatedAt: result.CreatedAt, ID: result.ID, Rank: &result.Rank}
+	})
+
+	authorIDs := make([]uuid.UUID, len(results))
+	for ix, result := range results {
+		authorIDs[ix] = result.UserID
+	}
+	authors, err := cfg.chirpAuthors(authorIDs)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	mappedResults := make([]ChirpSearchResult, len(results))
+	for ix, result := range results {
+		mappedResults[ix] = ChirpSearchResult{
+			Chirp:   Chirp{ID: result.ID, CreatedAt: result.CreatedAt, UpdatedAt: result.UpdatedAt, Body: result.Body, UserID: result.UserID, ParentID: result.ParentID, Author: authors[result.UserID], Edited: result.EditedAt.Valid},
+			Rank:    result.Rank,
+			Snippet: highlightSnippet(result.Snippet),
+		}
+	}
+
+	resultsBytes, _ := json.Marshal(ChirpSearchPage{Results: mappedResults, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, resultsBytes)
+}
+
 func (cfg *ApiConfig) HandleGetChirp(out http.ResponseWriter, req *http.Request) {
 	chirpID := req.PathValue("chirpID")
 	err := uuid.Validate(chirpID)
@@ -186,20 +436,23 @@ func (cfg *ApiConfig) HandleGetChirp(out http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	chirp, err := cfg.DB_Config.Queries.GetChirpByID(context.Background(), uuid.MustParse(chirpID))
+	chirp, err := cfg.DB_Config.Queries.GetVisibleChirpByID(context.Background(), uuid.MustParse(chirpID))
 
 	if err != nil {
-		RespondWithError(out, 404, err.Error())
+		RespondWithError(out, 404, "Chirp does not exist")
 		return
 	}
 
-	if chirp == (database.Chirp{}) {
-		RespondWithError(out, 404, "Chirp does not exist")
+	mappedChirps := []Chirp{FromDatabaseChirp(chirp)}
+	if err := cfg.addLikes(mappedChirps, viewerID(req)); err != nil {
+		RespondWithError(out, 400, err.Error())
 		return
 	}
-
-	mappedChirp := Chirp{ID: chirp.ID, CreatedAt: chirp.CreatedAt, UpdatedAt: chirp.UpdatedAt, UserID: chirp.ID, Body: chirp.Body}
-	jsonChirp, err := json.Marshal(mappedChirp)
+	if err := cfg.addAuthors(mappedChirps); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	jsonChirp, err := json.Marshal(mappedChirps[0])
 
 	if err != nil {
 		RespondWithError(out, 400, err.Error())
@@ -220,31 +473,51 @@ func (cfg *ApiConfig) HandleLogin(out http.ResponseWriter, req *http.Request) {
 		RespondWithError(out, 400, "Error handling login data")
 		return
 	}
+	loginKeys := []loginKey{accountLoginKey(parsedReqBody.Email), ipLoginKey(cfg.clientIP(req))}
+	if cfg.respondIfLocked(out, loginKeys...) {
+		return
+	}
 	usr, err := cfg.DB_Config.Queries.GetUserByEmail(context.Background(), parsedReqBody.Email)
 	if err != nil {
-		RespondWithError(out, 400, "User does not exist")
+		auth.CheckPasswordHash(parsedReqBody.Password, dummyPasswordHash())
+		cfg.recordLoginFailure(loginKeys...)
+		RespondWithError(out, 401, loginFailureMessage)
 		return
 	}
 	valid, _ := auth.CheckPasswordHash(parsedReqBody.Password, usr.HashedPassword)
 	if !valid {
-		RespondWithError(out, 401, "Wrong password")
+		cfg.recordLoginFailure(loginKeys...)
+		RespondWithError(out, 401, loginFailureMessage)
+		return
+	}
+
+	// With MFA, failures are only cleared once the code is right too, so
+	// the password alone can't reset the count of wrong codes.
+	if usr.TotpEnabledAt.Valid {
+		cfg.respondWithMFAChallenge(out, usr)
 		return
 	}
-	token, err := auth.CreateJWTToken(usr.ID, cfg.JWT_Secret, 3600*time.Second)
+	cfg.clearLoginFailures(usr)
+	cfg.respondWithNewSession(out, req, usr)
+}
+
+// respondWithNewSession finishes a login: it issues an access token and the
+// first refresh token of a new session.
+func (cfg *ApiConfig) respondWithNewSession(out http.ResponseWriter, req *http.Request, usr database.User) {
+	token, err := cfg.JWT_Keys.CreateJWTToken(usr.ID, usr.Role, 3600*time.Second)
 	if err != nil {
 		RespondWithError(out, 401, err.Error())
 		return
 	}
 
 	refreshToken, _ := auth.MakeRefreshToken()
-	refreshTokenDB, err := cfg.DB_Config.Queries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{Token: refreshToken, UserID: usr.ID})
+	_, err = cfg.DB_Config.Queries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{TokenHash: auth.HashToken(refreshToken), UserID: usr.ID, FamilyID: uuid.New(), UserAgent: userAgent(req), IpAddress: cfg.clientIP(req)})
 	if err != nil {
 		RespondWithError(out, 400, err.Error())
 		return
 	}
-	fmt.Printf("REFRESHTOKEN: %v", refreshToken)
 
-	user := FromDatabaseUser(usr, token, refreshTokenDB.Token)
+	user := FromDatabaseUser(usr, token, refreshToken)
 	jsonUser, err := json.Marshal(user)
 	if err != nil {
 		RespondWithError(out, 400, err.Error())
@@ -256,7 +529,8 @@ func (cfg *ApiConfig) HandleLogin(out http.ResponseWriter, req *http.Request) {
 
 func (cfg *ApiConfig) HandleRefreshToken(out http.ResponseWriter, req *http.Request) {
 	type tokenResponse struct {
-		Token string `json:"token"`
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
 	}
 	token := tokenResponse{}
 
@@ -267,36 +541,88 @@ func (cfg *ApiConfig) HandleRefreshToken(out http.ResponseWriter, req *http.Requ
 		return
 	}
 
-	refreshTokenData, err := cfg.DB_Config.Queries.GetRefreshTokenByToken(context.Background(), refreshToken)
+	refreshTokenData, err := cfg.DB_Config.Queries.GetRefreshTokenByHash(context.Background(), auth.HashToken(refreshToken))
 
 	if err != nil {
 		RespondWithError(out, 401, err.Error())
 		return
 	}
-	if refreshTokenData.RevokedAt != (sql.NullTime{}) {
+	if refreshTokenData.RevokedAt.Valid {
+		// A revoked token should never come back. If it does, someone else may
+		// hold a copy of it, so every session descended from it is ended.
+		cfg.DB_Config.Queries.RevokeRefreshTokenFamily(context.Background(), refreshTokenData.FamilyID)
+		RespondWithError(out, 401, "That refresh token is revoked")
+		return
+	}
+	if refreshTokenData.ExpiresAt.Before(time.Now()) {
+		RespondWithError(out, 401, "That refresh token is expired")
+		return
+	}
+	usr, err := cfg.DB_Config.Queries.GetUserByID(context.Background(), refreshTokenData.UserID)
+	if err != nil {
+		RespondWithError(out, 401, "User does not exist")
+		return
+	}
+
+	newRefreshToken, err := cfg.rotateRefreshToken(refreshTokenData, req)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another request rotated the token first: the same token was used twice.
+		cfg.DB_Config.Queries.RevokeRefreshTokenFamily(context.Background(), refreshTokenData.FamilyID)
 		RespondWithError(out, 401, "That refresh token is revoked")
 		return
 	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
 
-	jwt, err := auth.CreateJWTToken(refreshTokenData.UserID, cfg.JWT_Secret, 3600*time.Second)
+	jwt, err := cfg.JWT_Keys.CreateJWTToken(usr.ID, usr.Role, 3600*time.Second)
 	if err != nil {
 		RespondWithError(out, 401, err.Error())
 		return
 
 	}
 	token.Token = jwt
+	token.RefreshToken = newRefreshToken
 
 	resByte, _ := json.Marshal(token)
 	RespondWithJSON(out, 200, resByte)
 }
 
+// rotateRefreshToken revokes the presented token and issues its successor in
+// the same family. It returns sql.ErrNoRows if the token was already revoked.
+func (cfg *ApiConfig) rotateRefreshToken(current database.RefreshToken, req *http.Request) (string, error) {
+	newRefreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	newTokenHash := auth.HashToken(newRefreshToken)
+	_, err = queries.RotateRefreshToken(context.Background(), database.RotateRefreshTokenParams{TokenHash: current.TokenHash, ReplacedBy: newTokenHash})
+	if err != nil {
+		return "", err
+	}
+	_, err = queries.CreateRefreshToken(context.Background(), database.CreateRefreshTokenParams{TokenHash: newTokenHash, UserID: current.UserID, FamilyID: current.FamilyID, UserAgent: userAgent(req), IpAddress: cfg.clientIP(req)})
+	if err != nil {
+		return "", err
+	}
+	return newRefreshToken, tx.Commit()
+}
+
 func (cfg *ApiConfig) HandleRevokeToken(out http.ResponseWriter, req *http.Request) {
 	refreshToken, err := auth.GetBearerToken(req.Header)
 	if err != nil {
 		RespondWithError(out, 400, err.Error())
 		return
 	}
-	err = cfg.DB_Config.Queries.RevokeAccessToToken(context.Background(), refreshToken)
+	err = cfg.DB_Config.Queries.RevokeAccessToToken(context.Background(), auth.HashToken(refreshToken))
 	if err != nil {
 		RespondWithError(out, 400, err.Error())
 		return
@@ -311,23 +637,19 @@ func (cfg *ApiConfig) HandleUpdateUser(out http.ResponseWriter, req *http.Reques
 		Password string `json:"password"`
 	}
 	reqUpdateData := updateData{}
-	apiToken, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		RespondWithError(out, 401, "Token missing")
-		return
-	}
+	previousUser := requestUser(req)
+	userId := previousUser.ID
 	decoder := json.NewDecoder(req.Body)
 	decoder.Decode(&reqUpdateData)
 
-	userId, err := auth.ValidateJWT(apiToken, cfg.JWT_Secret)
-	if err != nil {
-		RespondWithError(out, 401, "Invalid Token")
+	if err := validateEmail(reqUpdateData.Email); err != nil {
+		RespondWithError(out, 400, err.Error())
 		return
 	}
 
 	hashedPassword, _ := auth.HashPassword(reqUpdateData.Password)
 
-	err = cfg.DB_Config.Queries.UpdateUser(context.Background(), database.UpdateUserParams{Email: reqUpdateData.Email, HashedPassword: hashedPassword, ID: userId})
+	err := cfg.DB_Config.Queries.UpdateUser(context.Background(), database.UpdateUserParams{Email: reqUpdateData.Email, HashedPassword: hashedPassword, ID: userId})
 	if err != nil {
 		RespondWithError(out, 401, "Problem while updating User")
 		fmt.Printf("%v", err)
@@ -335,6 +657,11 @@ func (cfg *ApiConfig) HandleUpdateUser(out http.ResponseWriter, req *http.Reques
 	}
 
 	updatedUser, _ := cfg.DB_Config.Queries.GetUserByID(context.Background(), userId)
+	if updatedUser.Email != previousUser.Email {
+		if err := cfg.sendEmailVerification(updatedUser); err != nil {
+			log.Printf("Error while creating email verification for %v: %v", updatedUser.ID, err)
+		}
+	}
 	mappedUpser := RegisterFromDatabaseUser(updatedUser)
 	parsedJsonUser, _ := json.Marshal(mappedUpser)
 
@@ -342,16 +669,7 @@ func (cfg *ApiConfig) HandleUpdateUser(out http.ResponseWriter, req *http.Reques
 }
 
 func (cfg *ApiConfig) HandleDeleteChirp(out http.ResponseWriter, req *http.Request) {
-	apiToken, err := auth.GetBearerToken(req.Header)
-	if err != nil {
-		RespondWithError(out, 401, "Token missing")
-		return
-	}
-	userId, err := auth.ValidateJWT(apiToken, cfg.JWT_Secret)
-	if err != nil {
-		RespondWithError(out, 401, "Invalid token")
-		return
-	}
+	userId := requestUser(req).ID
 
 	chirpID := req.PathValue("chirpID")
 	parsedChirp, err := uuid.Parse(chirpID)
@@ -362,7 +680,7 @@ func (cfg *ApiConfig) HandleDeleteChirp(out http.ResponseWriter, req *http.Reque
 
 	chirp, err := cfg.DB_Config.Queries.GetChirpByID(context.Background(), parsedChirp)
 
-	if err != nil {
+	if err != nil || chirp.DeletedAt.Valid {
 		RespondWithError(out, 404, "Chirp does not exist")
 		return
 	}
@@ -372,51 +690,159 @@ func (cfg *ApiConfig) HandleDeleteChirp(out http.ResponseWriter, req *http.Reque
 		return
 	}
 
-	err = cfg.DB_Config.Queries.DeleteChirpByID(context.Background(), chirp.ID)
-
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
 	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	if err := removeChirp(cfg.DB_Config.Queries.WithTx(tx), chirp.ID); err != nil {
 		RespondWithError(out, 400, err.Error())
 		return
 	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
 
 	RespondNoContent(out, 204)
 }
 
-func (cfg *ApiConfig) HandlePolkaWebhooks(out http.ResponseWriter, req *http.Request) {
-	type PolkaWebhookEvent struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+// respondIfNotEditable rejects edits of chirps that are deleted, hidden by a
+// moderator or written by someone else than userID. Hidden chirps look
+// deleted, so an edit can't undo the hide.
+func respondIfNotEditable(out http.ResponseWriter, chirp database.Chirp, userID uuid.UUID) bool {
+	if chirp.DeletedAt.Valid || chirp.HiddenAt.Valid {
+		RespondWithError(out, 404, "Chirp does not exist")
+		return true
 	}
-	apiKey, err := auth.GetAPIKey(req.Header)
-	if err != nil {
-		RespondWithError(out, 401, err.Error())
+	if chirp.UserID != userID {
+		RespondWithError(out, 403, "It isn't your chirp")
+		return true
+	}
+	return false
+}
+
+func (cfg *ApiConfig) HandleUpdateChirp(out http.ResponseWriter, req *http.Request) {
+	type updateChirpBody struct {
+		Body string `json:"body"`
+	}
+	usr := requestUser(req)
+	if respondIfSuspended(out, usr) {
+		return
+	}
+	plan := cfg.Plans.For(usr.IsChirpyRed)
+	if !plan.CanEditChirps {
+		RespondWithError(out, 403, "Editing chirps requires Chirpy Red")
 		return
 	}
-	if apiKey != cfg.POLKA_KEY {
-		RespondWithError(out, 401, "WRONG KEY")
+
+	parsedChirp, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid ChirpID")
 		return
 	}
-	webhookData := PolkaWebhookEvent{}
 
+	parsedReqBody := updateChirpBody{}
 	decoder := json.NewDecoder(req.Body)
-	decoder.Decode(&webhookData)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	chirpBody, err := cfg.ValidateChirp(parsedReqBody.Body, plan)
+	if err != nil {
+		respondWithValidationError(out, err)
+		return
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	chirp, err := queries.GetChirpByIDForUpdate(context.Background(), parsedChirp)
+	if err != nil {
+		RespondWithError(out, 404, "Chirp does not exist")
+		return
+	}
+	if respondIfNotEditable(out, chirp, usr.ID) {
+		return
+	}
 
-	switch webhookData.Event {
-	case "user.upgraded":
-		userId, _ := uuid.Parse(webhookData.Data.UserID)
-		err := cfg.DB_Config.Queries.UpgradeUserToRed(context.Background(), userId)
+	if chirp.Body != chirpBody {
+		if err := queries.CreateChirpRevision(context.Background(), chirp.ID); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+		chirp, err = queries.UpdateChirpBody(context.Background(), database.UpdateChirpBodyParams{Body: chirpBody, ID: chirp.ID})
 		if err != nil {
-			RespondWithError(out, 404, err.Error())
+			RespondWithError(out, 400, err.Error())
 			return
 		}
-		RespondNoContent(out, 204)
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
 		return
+	}
 
-	default:
-		RespondNoContent(out, 204)
+	mappedChirps := []Chirp{FromDatabaseChirp(chirp)}
+	if err := cfg.addAuthors(mappedChirps); err != nil {
+		RespondWithError(out, 400, err.Error())
 		return
 	}
+	jsonChirp, _ := json.Marshal(mappedChirps[0])
+	RespondWithJSON(out, 200, jsonChirp)
+}
 
+func (cfg *ApiConfig) HandleGetChirpRevisions(out http.ResponseWriter, req *http.Request) {
+	parsedChirp, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		RespondWithError(out, 400, "It's not valid chirp ID")
+		return
+	}
+	chirp, err := cfg.DB_Config.Queries.GetVisibleChirpByID(context.Background(), parsedChirp)
+	if err != nil || chirp.DeletedAt.Valid {
+		RespondWithError(out, 404, "Chirp does not exist")
+		return
+	}
+
+	revisions, err := cfg.DB_Config.Queries.GetChirpRevisions(context.Background(), chirp.ID)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	mappedRevisions := make([]ChirpRevision, len(revisions))
+	for ix, revision := range revisions {
+		mappedRevisions[ix] = FromDatabaseChirpRevision(revision)
+	}
+
+	revisionsBytes, _ := json.Marshal(mappedRevisions)
+	RespondWithJSON(out, 200, revisionsBytes)
+}
+
+// removeChirp deletes a chirp, or turns it into a tombstone when it has
+// replies.
+func removeChirp(queries *database.Queries, chirpID uuid.UUID) error {
+	replyCount, err := queries.CountChirpReplies(context.Background(), uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return err
+	}
+	if replyCount == 0 {
+		return queries.DeleteChirpByID(context.Background(), chirpID)
+	}
+	// Replies keep pointing at the chirp, so it stays in the thread as a
+	// tombstone.
+	return tombstoneChirp(queries, chirpID)
+}
+
+// tombstoneChirp removes the body and edit history of a chirp but keeps its
+// row.
+func tombstoneChirp(queries *database.Queries, chirpID uuid.UUID) error {
+	if err := queries.DeleteChirpRevisions(context.Background(), chirpID); err != nil {
+		return err
+	}
+	return queries.TombstoneChirp(context.Background(), chirpID)
 }
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+type contextKey int
+
+const userContextKey contextKey = iota
+
+// RequireAuth rejects requests without a valid access token. The user the
+// token belongs to is loaded once and put in the request context, where
+// handlers get it with UserFromContext.
+func (cfg *ApiConfig) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.GetBearerToken(r.Header); err != nil {
+			RespondWithError(w, 401, "Token missing")
+			return
+		}
+		usr, err := cfg.authenticate(r)
+		if err != nil {
+			RespondWithError(w, 401, "Invalid token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, usr)))
+	})
+}
+
+// OptionalAuth lets anonymous requests through without a user in the
+// context. A token that is sent but invalid is still rejected, so clients
+// notice expired tokens instead of silently getting anonymous results.
+func (cfg *ApiConfig) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := auth.GetBearerToken(r.Header); err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		usr, err := cfg.authenticate(r)
+		if err != nil {
+			RespondWithError(w, 401, "Invalid token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), userContextKey, usr)))
+	})
+}
+
+// RequireRole is RequireAuth that also rejects users below role. The role
+// is read from the database rather than the token, so a demoted user loses
+// access right away instead of when their token expires.
+func (cfg *ApiConfig) RequireRole(role string, next http.Handler) http.Handler {
+	return cfg.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !auth.HasRole(requestUser(r).Role, role) {
+			RespondWithError(w, 403, "Insufficient permissions")
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+func (cfg *ApiConfig) authenticate(req *http.Request) (database.User, error) {
+	apiToken, err := auth.GetBearerToken(req.Header)
+	if err != nil {
+		return database.User{}, err
+	}
+	userId, err := cfg.JWT_Keys.ValidateJWT(apiToken)
+	if err != nil {
+		return database.User{}, err
+	}
+	return cfg.DB_Config.Queries.GetUserByID(context.Background(), userId)
+}
+
+// UserFromContext returns the user that RequireAuth or OptionalAuth
+// authenticated for this request.
+func UserFromContext(ctx context.Context) (database.User, bool) {
+	usr, ok := ctx.Value(userContextKey).(database.User)
+	return usr, ok
+}
+
+// requestUser is UserFromContext for handlers registered behind
+// RequireAuth, where a missing user is a routing mistake.
+func requestUser(req *http.Request) database.User {
+	usr, ok := UserFromContext(req.Context())
+	if !ok {
+		panic("Handler for " + req.Pattern + " needs RequireAuth")
+	}
+	return usr
+}
+
+// viewerID returns the authenticated user of a request behind OptionalAuth,
+// or an invalid NullUUID for anonymous requests.
+func viewerID(req *http.Request) uuid.NullUUID {
+	usr, ok := UserFromContext(req.Context())
+	return uuid.NullUUID{UUID: usr.ID, Valid: ok}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/auth"
+)
+
+func TestOptionalAuthWithoutToken(t *testing.T) {
+	cfg := ApiConfig{JWT_Keys: auth.NewHMACKeySet("secret")}
+	called := false
+	handler := cfg.OptionalAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if viewerID(r).Valid {
+			t.Errorf("Anonymous request should have no viewer")
+		}
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/chirps", nil))
+	if !called {
+		t.Errorf("Anonymous request should reach the handler")
+	}
+}
+
+func TestAuthRejectsInvalidTokens(t *testing.T) {
+	cfg := ApiConfig{JWT_Keys: auth.NewHMACKeySet("secret")}
+	otherToken, _ := auth.NewHMACKeySet("other-secret").CreateJWTToken(uuid.New(), auth.RoleUser, time.Minute)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Request with an invalid token should not reach the handler")
+	})
+
+	for name, middleware := range map[string]func(http.Handler) http.Handler{"RequireAuth": cfg.RequireAuth, "OptionalAuth": cfg.OptionalAuth} {
+		req := httptest.NewRequest("GET", "/api/chirps", nil)
+		req.Header.Set("Authorization", "Bearer "+otherToken)
+		recorder := httptest.NewRecorder()
+		middleware(handler).ServeHTTP(recorder, req)
+		if recorder.Code != 401 {
+			t.Errorf("%v should answer 401 for an invalid token, got %d", name, recorder.Code)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	cfg.RequireAuth(handler).ServeHTTP(recorder, httptest.NewRequest("GET", "/api/timeline", nil))
+	if recorder.Code != 401 {
+		t.Errorf("RequireAuth should answer 401 without a token, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+// HandleBlockUser blocks a user and ends following in both directions.
+// Blocked users can't reply to, like or follow the blocker, and the
+// blocker no longer sees their chirps.
+func (cfg *ApiConfig) HandleBlockUser(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	blockedId, ok := cfg.parseOtherUserID(out, req, userId, "You can't block yourself")
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	if err := queries.BlockUser(context.Background(), database.BlockUserParams{BlockerID: userId, BlockedID: blockedId}); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := queries.UnfollowUser(context.Background(), database.UnfollowUserParams{FollowerID: userId, FollowedID: blockedId}); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := queries.UnfollowUser(context.Background(), database.UnfollowUserParams{FollowerID: blockedId, FollowedID: userId}); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func (cfg *ApiConfig) HandleUnblockUser(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	blockedId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "Invalid UserID")
+		return
+	}
+
+	err = cfg.DB_Config.Queries.UnblockUser(context.Background(), database.UnblockUserParams{BlockerID: userId, BlockedID: blockedId})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+// HandleMuteUser hides a user's chirps from the caller's listings and
+// timeline. Unlike a block, the muted user can still interact as before.
+func (cfg *ApiConfig) HandleMuteUser(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	mutedId, ok := cfg.parseOtherUserID(out, req, userId, "You can't mute yourself")
+	if !ok {
+		return
+	}
+
+	err := cfg.DB_Config.Queries.MuteUser(context.Background(), database.MuteUserParams{MuterID: userId, MutedID: mutedId})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func (cfg *ApiConfig) HandleUnmuteUser(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	mutedId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "Invalid UserID")
+		return
+	}
+
+	err = cfg.DB_Config.Queries.UnmuteUser(context.Background(), database.UnmuteUserParams{MuterID: userId, MutedID: mutedId})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+// parseOtherUserID reads the {userID} path value and checks that it is an
+// existing user other than the caller.
+func (cfg *ApiConfig) parseOtherUserID(out http.ResponseWriter, req *http.Request, userId uuid.UUID, selfMessage string) (uuid.UUID, bool) {
+	otherId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "Invalid UserID")
+		return uuid.Nil, false
+	}
+	if otherId == userId {
+		RespondWithError(out, 400, selfMessage)
+		return uuid.Nil, false
+	}
+	if _, err := cfg.DB_Config.Queries.GetUserByID(context.Background(), otherId); err != nil {
+		RespondWithError(out, 404, "User does not exist")
+		return uuid.Nil, false
+	}
+	return otherId, true
+}
+
+// respondIfBlocked rejects the request when the caller was blocked by
+// authorId.
+func (cfg *ApiConfig) respondIfBlocked(out http.ResponseWriter, authorId uuid.UUID, userId uuid.UUID, message string) bool {
+	blocked, err := cfg.DB_Config.Queries.IsBlocked(context.Background(), database.IsBlockedParams{BlockerID: authorId, BlockedID: userId})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return true
+	}
+	if blocked {
+		RespondWithError(out, 403, message)
+		return true
+	}
+	return false
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/censor"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+func (cfg *ApiConfig) HandleGetCensoredWords(out http.ResponseWriter, req *http.Request) {
+	words, err := cfg.DB_Config.Queries.GetCensoredWords(context.Background())
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	mappedWords := make([]CensoredWord, len(words))
+	for ix, word := range words {
+		mappedWords[ix] = FromDatabaseCensoredWord(word)
+	}
+	wordsBytes, _ := json.Marshal(mappedWords)
+	RespondWithJSON(out, 200, wordsBytes)
+}
+
+func (cfg *ApiConfig) HandleCreateCensoredWord(out http.ResponseWriter, req *http.Request) {
+	word := censor.Word{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&word); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if word.Strategy == "" {
+		word.Strategy = censor.StrategyMask
+	}
+	if err := word.Validate(); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	created, err := cfg.DB_Config.Queries.CreateCensoredWord(context.Background(), database.CreateCensoredWordParams{Pattern: word.Pattern, Strategy: string(word.Strategy), Replacement: word.Replacement})
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(out, 409, "Word is already censored")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := cfg.reloadCensor(context.Background()); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+
+	wordBytes, _ := json.Marshal(FromDatabaseCensoredWord(created))
+	RespondWithJSON(out, 201, wordBytes)
+}
+
+func (cfg *ApiConfig) HandleDeleteCensoredWord(out http.ResponseWriter, req *http.Request) {
+	wordID, err := uuid.Parse(req.PathValue("wordID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid WordID")
+		return
+	}
+	deleted, err := cfg.DB_Config.Queries.DeleteCensoredWord(context.Background(), wordID)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if deleted == 0 {
+		RespondWithError(out, 404, "Word does not exist")
+		return
+	}
+	if err := cfg.reloadCensor(context.Background()); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+// reloadCensor hands the words stored in the database to the censor.
+func (cfg *ApiConfig) reloadCensor(ctx context.Context) error {
+	storedWords, err := cfg.DB_Config.Queries.GetCensoredWords(ctx)
+	if err != nil {
+		return err
+	}
+	words := make([]censor.Word, 0, len(storedWords))
+	for _, storedWord := range storedWords {
+		words = append(words, censor.Word{Pattern: storedWord.Pattern, Strategy: censor.Strategy(storedWord.Strategy), Replacement: storedWord.Replacement})
+	}
+	return cfg.Censor.SetWords(words)
+}
+
+// RunCensorRefresher loads the censored words from the database right away
+// and then every interval until ctx is done, so changes made through another
+// instance are picked up too.
+func (cfg *ApiConfig) RunCensorRefresher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := cfg.reloadCensor(ctx); err != nil {
+			log.Printf("Error while loading censored words: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
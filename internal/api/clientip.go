@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseTrustedProxies parses a comma separated list of IP addresses and
+// CIDR ranges, like "10.0.0.0/8,192.168.1.10".
+func ParseTrustedProxies(value string) ([]netip.Prefix, error) {
+	proxies := []netip.Prefix{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, err
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+	return proxies, nil
+}
+
+func (cfg *ApiConfig) isTrustedProxy(addr netip.Addr) bool {
+	for _, proxy := range cfg.TrustedProxies {
+		if proxy.Contains(addr.Unmap()) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the address of the client that sent req. It is the peer
+// that opened the connection, unless that peer is a trusted proxy: then it is
+// the right-most address in X-Forwarded-For that isn't a trusted proxy.
+// Addresses left of it could have been made up by the client.
+func (cfg *ApiConfig) clientIP(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+	client, err := netip.ParseAddr(host)
+	if err != nil || !cfg.isTrustedProxy(client) {
+		return host
+	}
+
+	hops := strings.Split(strings.Join(req.Header.Values("X-Forwarded-For"), ","), ",")
+	for ix := len(hops) - 1; ix >= 0; ix-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[ix]))
+		if err != nil {
+			break
+		}
+		client = hop.Unmap()
+		if !cfg.isTrustedProxy(client) {
+			break
+		}
+	}
+	return client.String()
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("10.0.0.0/8, 192.168.1.10")
+	if err != nil {
+		t.Fatalf("Trusted proxies should parse, got %v", err)
+	}
+	cfg := ApiConfig{TrustedProxies: proxies}
+	cases := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expected     string
+		withoutProxy string
+	}{
+		{"direct", "203.0.113.7:5000", nil, "203.0.113.7", "203.0.113.7"},
+		{"spoofed header", "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7", "203.0.113.7"},
+		{"behind proxy", "10.0.0.5:5000", []string{"203.0.113.7"}, "203.0.113.7", "10.0.0.5"},
+		{"spoofed header behind proxy", "10.0.0.5:5000", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7", "10.0.0.5"},
+		{"chained proxies", "192.168.1.10:5000", []string{"198.51.100.1, 203.0.113.7", "10.1.2.3"}, "203.0.113.7", "192.168.1.10"},
+		{"proxy without header", "10.0.0.5:5000", nil, "10.0.0.5", "10.0.0.5"},
+		{"invalid header", "10.0.0.5:5000", []string{"203.0.113.7, not-an-ip"}, "10.0.0.5", "10.0.0.5"},
+	}
+	for _, testCase := range cases {
+		req := httptest.NewRequest("POST", "/api/login", nil)
+		req.RemoteAddr = testCase.remoteAddr
+		for _, value := range testCase.forwardedFor {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+		if ip := cfg.clientIP(req); ip != testCase.expected {
+			t.Errorf("%v: client IP should be %v, got %v", testCase.name, testCase.expected, ip)
+		}
+		if ip := (&ApiConfig{}).clientIP(req); ip != testCase.withoutProxy {
+			t.Errorf("%v: without trusted proxies, client IP should be %v, got %v", testCase.name, testCase.withoutProxy, ip)
+		}
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalidEntries(t *testing.T) {
+	if _, err := ParseTrustedProxies("10.0.0.0/8,proxy.local"); err == nil {
+		t.Errorf("Host names should be rejected")
+	}
+	if proxies, err := ParseTrustedProxies(""); err != nil || len(proxies) != 0 {
+		t.Errorf("An empty value should mean no trusted proxies, got %v, %v", proxies, err)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	netmail "net/mail"
+	"net/url"
+	"time"
+
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+	"github.com/widua/go-http-server/internal/mail"
+)
+
+const mailTimeout = 30 * time.Second
+
+// sendMail delivers msg in the background, so a slow mail server neither
+// delays the response nor reveals through timing whether an account exists.
+func (cfg *ApiConfig) sendMail(msg mail.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := cfg.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("Error while sending mail %q: %v", msg.Subject, err)
+		}
+	}()
+}
+
+// validateEmail accepts a bare address like user@example.com. Display names
+// and angle brackets are rejected, as the value is used as-is as the login.
+func validateEmail(email string) error {
+	address, err := netmail.ParseAddress(email)
+	if err != nil || address.Name != "" || address.Address != email {
+		return errors.New("Invalid email address")
+	}
+	return nil
+}
+
+func (cfg *ApiConfig) sendEmailVerification(usr database.User) error {
+	verificationToken, err := auth.MakeOneTimeToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.DB_Config.Queries.CreateEmailVerificationToken(context.Background(), database.CreateEmailVerificationTokenParams{TokenHash: auth.HashToken(verificationToken), Email: usr.Email, UserID: usr.ID})
+	if err != nil {
+		return err
+	}
+
+	cfg.sendMail(mail.Message{
+		To:      usr.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: "Open this link within the next 24 hours to verify your email address:\n\n" +
+			cfg.BaseURL + "/api/users/verify?token=" + url.QueryEscape(verificationToken) + "\n\n" +
+			"If you didn't sign up for Chirpy, you can ignore this email.\n",
+	})
+	return nil
+}
+
+func (cfg *ApiConfig) HandleVerifyEmail(out http.ResponseWriter, req *http.Request) {
+	verificationToken := req.URL.Query().Get("token")
+	if verificationToken == "" {
+		RespondWithError(out, 400, "Token is required")
+		return
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	usedToken, err := queries.UseEmailVerificationToken(context.Background(), auth.HashToken(verificationToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(out, 401, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	// The token is bound to the address it was sent to, so it can't verify
+	// an email the user switched to afterwards.
+	verified, err := queries.MarkUserEmailVerified(context.Background(), database.MarkUserEmailVerifiedParams{ID: usedToken.UserID, Email: usedToken.Email})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if verified == 0 {
+		RespondWithError(out, 409, "Email address has changed since the token was sent")
+		return
+	}
+	usr, err := queries.GetUserByID(context.Background(), usedToken.UserID)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+
+	userBytes, _ := json.Marshal(RegisterFromDatabaseUser(usr))
+	RespondWithJSON(out, 200, userBytes)
+}
+
+func (cfg *ApiConfig) HandleResendEmailVerification(out http.ResponseWriter, req *http.Request) {
+	usr := requestUser(req)
+	if usr.EmailVerifiedAt.Valid {
+		RespondWithError(out, 409, "Email address is already verified")
+		return
+	}
+	if err := cfg.sendEmailVerification(usr); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	RespondNoContent(out, 202)
+}
@@ -0,0 +1,16 @@
+package api
+
+import "testing"
+
+func TestValidateEmail(t *testing.T) {
+	for _, email := range []string{"user@example.com", "first.last+tag@sub.example.org"} {
+		if err := validateEmail(email); err != nil {
+			t.Errorf("%q should be a valid email, but got error: %v", email, err)
+		}
+	}
+	for _, email := range []string{"", "not-an-email", "@example.com", "user@", "User <user@example.com>", " user@example.com", "user@example.com\r\nBcc: x@example.com"} {
+		if err := validateEmail(email); err == nil {
+			t.Errorf("%q should be rejected", email)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+func (cfg *ApiConfig) HandleFollowUser(out http.ResponseWriter, req *http.Request) {
+	usr := requestUser(req)
+	if respondIfSuspended(out, usr) {
+		return
+	}
+	userId := usr.ID
+	followedId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "Invalid UserID")
+		return
+	}
+	if followedId == userId {
+		RespondWithError(out, 400, "You can't follow yourself")
+		return
+	}
+	if _, err := cfg.DB_Config.Queries.GetUserByID(context.Background(), followedId); err != nil {
+		RespondWithError(out, 404, "User does not exist")
+		return
+	}
+	if cfg.respondIfBlocked(out, followedId, userId, "You can't follow this user") {
+		return
+	}
+
+	err = cfg.DB_Config.Queries.FollowUser(context.Background(), database.FollowUserParams{FollowerID: userId, FollowedID: followedId})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func (cfg *ApiConfig) HandleUnfollowUser(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	followedId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "Invalid UserID")
+		return
+	}
+
+	err = cfg.DB_Config.Queries.UnfollowUser(context.Background(), database.UnfollowUserParams{FollowerID: userId, FollowedID: followedId})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func (cfg *ApiConfig) HandleGetFollowers(out http.ResponseWriter, req *http.Request) {
+	userId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "Invalid UserID")
+		return
+	}
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	followers, err := cfg.DB_Config.Queries.GetFollowers(context.Background(), database.GetFollowersParams{UserID: userId, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	followers, nextCursor := paginate(followers, page, func(follower database.GetFollowersRow) pageCursor {
+		return pageCursor{CreatedAt: follower.CreatedAt, ID: follower.UserID}
+	})
+
+	mappedFollowers := make([]Follow, len(followers))
+	for ix, follower := range followers {
+		mappedFollowers[ix] = Follow{UserID: follower.UserID, FollowedAt: follower.CreatedAt}
+	}
+	followersBytes, _ := json.Marshal(FollowPage{Users: mappedFollowers, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, followersBytes)
+}
+
+func (cfg *ApiConfig) HandleGetFollowing(out http.ResponseWriter, req *http.Request) {
+	userId, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "Invalid UserID")
+		return
+	}
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	following, err := cfg.DB_Config.Queries.GetFollowing(context.Background(), database.GetFollowingParams{UserID: userId, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	following, nextCursor := paginate(following, page, func(followed database.GetFollowingRow) pageCursor {
+		return pageCursor{CreatedAt: followed.CreatedAt, ID: followed.UserID}
+	})
+
+	mappedFollowing := make([]Follow, len(following))
+	for ix, followed := range following {
+		mappedFollowing[ix] = Follow{UserID: followed.UserID, FollowedAt: followed.CreatedAt}
+	}
+	followingBytes, _ := json.Marshal(FollowPage{Users: mappedFollowing, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, followingBytes)
+}
+
+func (cfg *ApiConfig) HandleGetTimeline(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	chirps, err := cfg.DB_Config.Queries.GetTimeline(context.Background(), database.GetTimelineParams{UserID: userId, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	chirps, nextCursor := paginate(chirps, page, func(chirp database.Chirp) pageCursor {
+		return pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	})
+
+	mappedChirps := make([]Chirp, len(chirps))
+	for ix, chirp := range chirps {
+		mappedChirps[ix] = FromDatabaseChirp(chirp)
+	}
+	if err := cfg.addLikes(mappedChirps, uuid.NullUUID{UUID: userId, Valid: true}); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := cfg.addAuthors(mappedChirps); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	chirpsBytes, _ := json.Marshal(ChirpPage{Chirps: mappedChirps, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, chirpsBytes)
+}
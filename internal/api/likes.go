@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+func (cfg *ApiConfig) HandleLikeChirp(out http.ResponseWriter, req *http.Request) {
+	usr := requestUser(req)
+	if respondIfSuspended(out, usr) {
+		return
+	}
+	userId := usr.ID
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid ChirpID")
+		return
+	}
+	chirp, err := cfg.DB_Config.Queries.GetVisibleChirpByID(context.Background(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		RespondWithError(out, 404, "Chirp does not exist")
+		return
+	}
+	if cfg.respondIfBlocked(out, chirp.UserID, userId, "You can't like this user's chirps") {
+		return
+	}
+
+	err = cfg.DB_Config.Queries.LikeChirp(context.Background(), database.LikeChirpParams{UserID: userId, ChirpID: chirp.ID})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func (cfg *ApiConfig) HandleUnlikeChirp(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid ChirpID")
+		return
+	}
+
+	err = cfg.DB_Config.Queries.UnlikeChirp(context.Background(), database.UnlikeChirpParams{UserID: userId, ChirpID: chirpID})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+// addLikes fills like_count and, for an authenticated viewer, liked_by_me
+// on a page of chirps with two batched queries.
+func (cfg *ApiConfig) addLikes(chirps []Chirp, viewer uuid.NullUUID) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for ix, chirp := range chirps {
+		chirpIDs[ix] = chirp.ID
+	}
+
+	likeCounts, err := cfg.DB_Config.Queries.GetChirpLikeCounts(context.Background(), chirpIDs)
+	if err != nil {
+		return err
+	}
+	countByChirp := make(map[uuid.UUID]int64, len(likeCounts))
+	for _, likeCount := range likeCounts {
+		countByChirp[likeCount.ChirpID] = likeCount.LikeCount
+	}
+
+	likedByViewer := make(map[uuid.UUID]bool)
+	if viewer.Valid {
+		likedIDs, err := cfg.DB_Config.Queries.GetLikedChirpIDs(context.Background(), database.GetLikedChirpIDsParams{UserID: viewer.UUID, ChirpIds: chirpIDs})
+		if err != nil {
+			return err
+		}
+		for _, likedID := range likedIDs {
+			likedByViewer[likedID] = true
+		}
+	}
+
+	for ix := range chirps {
+		chirps[ix].LikeCount = countByChirp[chirps[ix].ID]
+		chirps[ix].LikedByMe = likedByViewer[chirps[ix].ID]
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+// loginFailureMessage is the only error a failed login gets, whether the
+// account exists or not, so logins can't be used to find registered emails.
+const loginFailureMessage = "Incorrect email or password"
+
+type lockoutPolicy struct {
+	// freeAttempts is how many failures are allowed before any lockout.
+	freeAttempts int32
+	baseLockout  time.Duration
+	maxLockout   time.Duration
+}
+
+var (
+	accountLockoutPolicy = lockoutPolicy{freeAttempts: 5, baseLockout: time.Minute, maxLockout: time.Hour}
+	// Many users can share an IP behind NAT, so addresses get more slack.
+	ipLockoutPolicy = lockoutPolicy{freeAttempts: 20, baseLockout: time.Minute, maxLockout: time.Hour}
+)
+
+// lockoutFor returns how long logins are locked after the given number of
+// consecutive failures. The lockout doubles with every failure past the free
+// attempts, up to maxLockout.
+func (p lockoutPolicy) lockoutFor(failures int32) time.Duration {
+	if failures <= p.freeAttempts {
+		return 0
+	}
+	lockout := p.baseLockout
+	for ix := p.freeAttempts + 1; ix < failures && lockout < p.maxLockout; ix++ {
+		lockout *= 2
+	}
+	return min(lockout, p.maxLockout)
+}
+
+type loginKey struct {
+	key    string
+	policy lockoutPolicy
+}
+
+func accountLoginKey(email string) loginKey {
+	return loginKey{key: "email:" + strings.ToLower(strings.TrimSpace(email)), policy: accountLockoutPolicy}
+}
+
+func ipLoginKey(ip string) loginKey {
+	return loginKey{key: "ip:" + ip, policy: ipLockoutPolicy}
+}
+
+// dummyPasswordHash is checked when the account doesn't exist, so the
+// response takes as long as for a wrong password.
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := auth.HashPassword("chirpy-dummy-password")
+	return hash
+})
+
+// respondIfLocked answers 429 and returns true if any of the keys is
+// currently locked out.
+func (cfg *ApiConfig) respondIfLocked(out http.ResponseWriter, keys ...loginKey) bool {
+	rawKeys := make([]string, len(keys))
+	for ix, key := range keys {
+		rawKeys[ix] = key.key
+	}
+	retryAfter, err := cfg.DB_Config.Queries.GetLoginLockoutSeconds(context.Background(), rawKeys)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return true
+	}
+	if retryAfter <= 0 {
+		return false
+	}
+	out.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+	RespondWithError(out, 429, "Too many failed login attempts, try again later")
+	return true
+}
+
+func (cfg *ApiConfig) recordLoginFailure(keys ...loginKey) {
+	for _, key := range keys {
+		failures, err := cfg.DB_Config.Queries.RecordLoginFailure(context.Background(), key.key)
+		if err != nil {
+			log.Printf("Error while recording login failure: %v", err)
+			continue
+		}
+		if lockout := key.policy.lockoutFor(failures); lockout > 0 {
+			err := cfg.DB_Config.Queries.LockLogin(context.Background(), database.LockLoginParams{LockSeconds: int32(lockout / time.Second), Key: key.key})
+			if err != nil {
+				log.Printf("Error while locking login: %v", err)
+			}
+		}
+	}
+}
+
+// clearLoginFailures forgets the failures of an account after a successful
+// login. Failures by IP are kept, otherwise an attacker could reset them by
+// logging into an account of their own.
+func (cfg *ApiConfig) clearLoginFailures(usr database.User) {
+	if err := cfg.DB_Config.Queries.ClearLoginFailures(context.Background(), accountLoginKey(usr.Email).key); err != nil {
+		log.Printf("Error while clearing login failures of %v: %v", usr.ID, err)
+	}
+}
+
+func (cfg *ApiConfig) HandleUnlockUser(out http.ResponseWriter, req *http.Request) {
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 400, "It's not valid user ID")
+		return
+	}
+	usr, err := cfg.DB_Config.Queries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		RespondWithError(out, 404, "User does not exist")
+		return
+	}
+
+	if err := cfg.DB_Config.Queries.ClearLoginFailures(context.Background(), accountLoginKey(usr.Email).key); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutFor(t *testing.T) {
+	policy := lockoutPolicy{freeAttempts: 3, baseLockout: time.Minute, maxLockout: 10 * time.Minute}
+	cases := map[int32]time.Duration{
+		1:  0,
+		3:  0,
+		4:  time.Minute,
+		5:  2 * time.Minute,
+		6:  4 * time.Minute,
+		7:  8 * time.Minute,
+		8:  10 * time.Minute,
+		50: 10 * time.Minute,
+	}
+	for failures, expected := range cases {
+		if lockout := policy.lockoutFor(failures); lockout != expected {
+			t.Errorf("Lockout after %d failures should be %v, but is %v", failures, expected, lockout)
+		}
+	}
+}
+
+func TestAccountLoginKeyIsCaseInsensitive(t *testing.T) {
+	if accountLoginKey(" User@Example.com").key != accountLoginKey("user@example.com").key {
+		t.Errorf("Login failures of the same email in different case should share a key")
+	}
+}
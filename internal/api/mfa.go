@@ -0,0 +1,189 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+const (
+	mfaIssuer            = "Chirpy"
+	mfaChallengeExpiry   = 5 * time.Minute
+	mfaRecoveryCodeCount = 10
+)
+
+func (cfg *ApiConfig) HandleMFASetup(out http.ResponseWriter, req *http.Request) {
+	usr := requestUser(req)
+	if usr.TotpEnabledAt.Valid {
+		RespondWithError(out, 409, "Two-factor authentication is already enabled")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	err = cfg.DB_Config.Queries.SetUserTOTPSecret(context.Background(), database.SetUserTOTPSecretParams{TotpSecret: sql.NullString{String: secret, Valid: true}, ID: usr.ID})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	setupBytes, _ := json.Marshal(MFASetup{Secret: secret, OTPAuthURI: auth.TOTPAuthURI(secret, usr.Email, mfaIssuer)})
+	RespondWithJSON(out, 200, setupBytes)
+}
+
+// HandleMFAVerify turns two-factor authentication on once the user proves
+// their authenticator app produces valid codes for the pending secret.
+func (cfg *ApiConfig) HandleMFAVerify(out http.ResponseWriter, req *http.Request) {
+	type verifyBody struct {
+		Code string `json:"code"`
+	}
+	usr := requestUser(req)
+	parsedReqBody := verifyBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	if usr.TotpEnabledAt.Valid {
+		RespondWithError(out, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	if !usr.TotpSecret.Valid {
+		RespondWithError(out, 400, "Two-factor authentication setup was not started")
+		return
+	}
+	step, valid := auth.ValidateTOTP(usr.TotpSecret.String, parsedReqBody.Code, time.Now(), 0)
+	if !valid {
+		RespondWithError(out, 401, "Invalid code")
+		return
+	}
+
+	recoveryCodes, err := auth.GenerateRecoveryCodes(mfaRecoveryCodeCount)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	if err := queries.DeleteRecoveryCodes(context.Background(), usr.ID); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	for _, code := range recoveryCodes {
+		codeHash, err := auth.HashPassword(code)
+		if err != nil {
+			RespondWithError(out, 500, err.Error())
+			return
+		}
+		if err := queries.CreateRecoveryCode(context.Background(), database.CreateRecoveryCodeParams{CodeHash: codeHash, UserID: usr.ID}); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+	}
+	if err := queries.EnableUserTOTP(context.Background(), database.EnableUserTOTPParams{TotpLastUsedStep: step, ID: usr.ID}); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+
+	codesBytes, _ := json.Marshal(MFARecoveryCodes{RecoveryCodes: recoveryCodes})
+	RespondWithJSON(out, 200, codesBytes)
+}
+
+// HandleLoginMFA is the second step of a login for users with two-factor
+// authentication: the challenge token from POST /api/login is exchanged,
+// together with a TOTP or recovery code, for the usual tokens.
+func (cfg *ApiConfig) HandleLoginMFA(out http.ResponseWriter, req *http.Request) {
+	type loginMFABody struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	parsedReqBody := loginMFABody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, "Error handling login data")
+		return
+	}
+	userId, err := cfg.JWT_Keys.ValidateMFAChallengeToken(parsedReqBody.MFAToken)
+	if err != nil {
+		RespondWithError(out, 401, "Invalid or expired MFA token")
+		return
+	}
+	usr, err := cfg.DB_Config.Queries.GetUserByID(context.Background(), userId)
+	if err != nil || !usr.TotpEnabledAt.Valid {
+		RespondWithError(out, 401, "Invalid or expired MFA token")
+		return
+	}
+	// Wrong codes count like wrong passwords, otherwise the six digit code
+	// could be brute forced within the lifetime of the challenge.
+	loginKeys := []loginKey{accountLoginKey(usr.Email), ipLoginKey(cfg.clientIP(req))}
+	if cfg.respondIfLocked(out, loginKeys...) {
+		return
+	}
+
+	valid, err := cfg.checkMFACode(usr, parsedReqBody.Code)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if !valid {
+		cfg.recordLoginFailure(loginKeys...)
+		RespondWithError(out, 401, "Invalid code")
+		return
+	}
+	cfg.clearLoginFailures(usr)
+	cfg.respondWithNewSession(out, req, usr)
+}
+
+func (cfg *ApiConfig) respondWithMFAChallenge(out http.ResponseWriter, usr database.User) {
+	mfaToken, err := cfg.JWT_Keys.CreateMFAChallengeToken(usr.ID, mfaChallengeExpiry)
+	if err != nil {
+		RespondWithError(out, 401, err.Error())
+		return
+	}
+	challengeBytes, _ := json.Marshal(MFAChallenge{MFARequired: true, MFAToken: mfaToken})
+	RespondWithJSON(out, 200, challengeBytes)
+}
+
+// checkMFACode accepts either a current TOTP code or an unused recovery
+// code. Both are consumed, so neither can be used twice.
+func (cfg *ApiConfig) checkMFACode(usr database.User, code string) (bool, error) {
+	if step, valid := auth.ValidateTOTP(usr.TotpSecret.String, code, time.Now(), usr.TotpLastUsedStep); valid {
+		updated, err := cfg.DB_Config.Queries.UseUserTOTPStep(context.Background(), database.UseUserTOTPStepParams{TotpLastUsedStep: step, ID: usr.ID})
+		return updated == 1, err
+	}
+
+	recoveryCode := auth.NormalizeRecoveryCode(code)
+	if recoveryCode == "" {
+		return false, nil
+	}
+	storedCodes, err := cfg.DB_Config.Queries.GetUnusedRecoveryCodes(context.Background(), usr.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, storedCode := range storedCodes {
+		if matches, _ := auth.CheckPasswordHash(recoveryCode, storedCode.CodeHash); matches {
+			used, err := cfg.DB_Config.Queries.UseRecoveryCode(context.Background(), storedCode.ID)
+			return used == 1, err
+		}
+	}
+	return false, nil
+}
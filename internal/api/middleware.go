@@ -1,6 +1,16 @@
 package api
 
-import "net/http"
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/ratelimit"
+)
 
 func (cfg *ApiConfig) MetricsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -8,3 +18,44 @@ func (cfg *ApiConfig) MetricsMiddleware(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+// RateLimitMiddleware limits requests per user, or per IP address for
+// requests without a valid access token. If the store fails, requests are
+// let through rather than taking the API down with it.
+func (cfg *ApiConfig) RateLimitMiddleware(policy ratelimit.Policy, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cfg.RateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		result, err := cfg.RateLimiter.Take(context.Background(), cfg.rateLimitKey(r), policy, time.Now())
+		if err != nil {
+			log.Printf("Error while rate limiting %v: %v", policy.Name, err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", fmt.Sprint(result.Limit))
+		w.Header().Set("RateLimit-Remaining", fmt.Sprint(result.Remaining))
+		w.Header().Set("RateLimit-Reset", fmt.Sprint(ceilSeconds(result.Reset)))
+		if !result.Allowed {
+			w.Header().Set("Retry-After", fmt.Sprint(ceilSeconds(result.RetryAfter)))
+			RespondWithError(w, 429, "Too many requests")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (cfg *ApiConfig) rateLimitKey(req *http.Request) string {
+	if token, err := auth.GetBearerToken(req.Header); err == nil {
+		if userId, err := cfg.JWT_Keys.ValidateJWT(token); err == nil {
+			return "user:" + userId.String()
+		}
+	}
+	return "ip:" + cfg.clientIP(req)
+}
+
+func ceilSeconds(duration time.Duration) int64 {
+	return int64(math.Ceil(duration.Seconds()))
+}
@@ -0,0 +1,440 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+	"github.com/widua/go-http-server/internal/mail"
+)
+
+const (
+	maxReportDetailsLength = 1000
+	maxSuspensionHours     = 24 * 365
+
+	reportOpen      = "open"
+	reportResolved  = "resolved"
+	reportDismissed = "dismissed"
+
+	actionHideChirp     = "hide_chirp"
+	actionUnhideChirp   = "unhide_chirp"
+	actionDeleteChirp   = "delete_chirp"
+	actionWarnUser      = "warn_user"
+	actionSuspendUser   = "suspend_user"
+	actionUnsuspendUser = "unsuspend_user"
+	actionDismissReport = "dismiss_report"
+)
+
+var reportReasons = []string{"spam", "harassment", "hate", "violence", "misinformation", "other"}
+
+// respondIfSuspended rejects the request when usr is suspended. Suspended
+// users can still read and manage their account, but not post or interact.
+func respondIfSuspended(out http.ResponseWriter, usr database.User) bool {
+	if !usr.SuspendedUntil.Valid || !usr.SuspendedUntil.Time.After(time.Now()) {
+		return false
+	}
+	RespondWithError(out, 403, "Account is suspended until "+usr.SuspendedUntil.Time.UTC().Format(time.RFC3339))
+	return true
+}
+
+func (cfg *ApiConfig) HandleReportChirp(out http.ResponseWriter, req *http.Request) {
+	type reportBody struct {
+		Reason  string `json:"reason"`
+		Details string `json:"details"`
+	}
+	usr := requestUser(req)
+	if respondIfSuspended(out, usr) {
+		return
+	}
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid ChirpID")
+		return
+	}
+	parsedReqBody := reportBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if !slices.Contains(reportReasons, parsedReqBody.Reason) {
+		RespondWithError(out, 400, "Reason must be one of "+strings.Join(reportReasons, ", "))
+		return
+	}
+	if utf8.RuneCountInString(parsedReqBody.Details) > maxReportDetailsLength {
+		RespondWithError(out, 400, fmt.Sprintf("Details can be at most %d characters long", maxReportDetailsLength))
+		return
+	}
+
+	chirp, err := cfg.DB_Config.Queries.GetVisibleChirpByID(context.Background(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		RespondWithError(out, 404, "Chirp does not exist")
+		return
+	}
+	if chirp.UserID == usr.ID {
+		RespondWithError(out, 400, "You can't report your own chirp")
+		return
+	}
+
+	report, err := cfg.DB_Config.Queries.CreateReport(context.Background(), database.CreateReportParams{ChirpID: chirp.ID, ReporterID: usr.ID, Reason: parsedReqBody.Reason, Details: parsedReqBody.Details})
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(out, 409, "You already reported this chirp")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	reportBytes, _ := json.Marshal(FromDatabaseReport(report))
+	RespondWithJSON(out, 201, reportBytes)
+}
+
+// HandleGetReports is the moderation queue: reports with the given status,
+// open by default, oldest first.
+func (cfg *ApiConfig) HandleGetReports(out http.ResponseWriter, req *http.Request) {
+	status := req.URL.Query().Get("status")
+	if status == "" {
+		status = reportOpen
+	}
+	if status != reportOpen && status != reportResolved && status != reportDismissed {
+		RespondWithError(out, 400, "status must be open, resolved or dismissed")
+		return
+	}
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	reports, err := cfg.DB_Config.Queries.GetReports(context.Background(), database.GetReportsParams{Status: status, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	reports, nextCursor := paginate(reports, page, func(report database.GetReportsRow) pageCursor {
+		return pageCursor{CreatedAt: report.Report.CreatedAt, ID: report.Report.ID}
+	})
+
+	reportedChirps := make([]Chirp, len(reports))
+	for ix, report := range reports {
+		reportedChirps[ix] = FromDatabaseChirp(report.Chirp)
+	}
+	if err := cfg.addAuthors(reportedChirps); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	mappedReports := make([]QueuedReport, len(reports))
+	for ix, report := range reports {
+		mappedReports[ix] = QueuedReport{Report: FromDatabaseReport(report.Report), Chirp: reportedChirps[ix]}
+	}
+	reportsBytes, _ := json.Marshal(ReportPage{Reports: mappedReports, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, reportsBytes)
+}
+
+func (cfg *ApiConfig) HandleDismissReport(out http.ResponseWriter, req *http.Request) {
+	type dismissBody struct {
+		Reason string `json:"reason"`
+	}
+	moderator := requestUser(req)
+	reportID, err := uuid.Parse(req.PathValue("reportID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid ReportID")
+		return
+	}
+	parsedReqBody := dismissBody{}
+	if err := decodeOptionalBody(req, &parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	report, err := queries.GetReportByID(context.Background(), reportID)
+	if err != nil {
+		RespondWithError(out, 404, "Report does not exist")
+		return
+	}
+	dismissed, err := queries.DismissReport(context.Background(), database.DismissReportParams{ID: report.ID, ResolvedBy: uuid.NullUUID{UUID: moderator.ID, Valid: true}})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if dismissed == 0 {
+		RespondWithError(out, 409, "Report is already closed")
+		return
+	}
+	action, err := queries.CreateModerationAction(context.Background(), database.CreateModerationActionParams{
+		ModeratorID: moderator.ID,
+		Action:      actionDismissReport,
+		ChirpID:     uuid.NullUUID{UUID: report.ChirpID, Valid: true},
+		ReportID:    uuid.NullUUID{UUID: report.ID, Valid: true},
+		Reason:      parsedReqBody.Reason,
+	})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+
+	actionBytes, _ := json.Marshal(FromDatabaseModerationAction(action))
+	RespondWithJSON(out, 200, actionBytes)
+}
+
+// HandleModerateChirp hides, unhides or deletes a chirp. Hiding or deleting
+// resolves the open reports about it.
+func (cfg *ApiConfig) HandleModerateChirp(out http.ResponseWriter, req *http.Request) {
+	type moderateChirpBody struct {
+		Action string `json:"action"`
+		Reason string `json:"reason"`
+	}
+	moderator := requestUser(req)
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid ChirpID")
+		return
+	}
+	parsedReqBody := moderateChirpBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	chirp, err := queries.GetChirpByIDForUpdate(context.Background(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		RespondWithError(out, 404, "Chirp does not exist")
+		return
+	}
+	resolvedBy := uuid.NullUUID{UUID: moderator.ID, Valid: true}
+
+	var action string
+	switch parsedReqBody.Action {
+	case "hide":
+		action = actionHideChirp
+		_, err = queries.SetChirpHidden(context.Background(), database.SetChirpHiddenParams{Hidden: true, ID: chirp.ID})
+		if err == nil {
+			_, err = queries.ResolveChirpReports(context.Background(), database.ResolveChirpReportsParams{ChirpID: chirp.ID, ResolvedBy: resolvedBy})
+		}
+	case "unhide":
+		action = actionUnhideChirp
+		_, err = queries.SetChirpHidden(context.Background(), database.SetChirpHiddenParams{Hidden: false, ID: chirp.ID})
+	case "delete":
+		action = actionDeleteChirp
+		_, err = queries.ResolveChirpReports(context.Background(), database.ResolveChirpReportsParams{ChirpID: chirp.ID, ResolvedBy: resolvedBy})
+		// The row is kept even without replies, as deleting it would
+		// also delete the reports about it.
+		if err == nil {
+			err = tombstoneChirp(queries, chirp.ID)
+		}
+	default:
+		RespondWithError(out, 400, "Action must be hide, unhide or delete")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	auditEntry, err := queries.CreateModerationAction(context.Background(), database.CreateModerationActionParams{
+		ModeratorID: moderator.ID,
+		Action:      action,
+		ChirpID:     uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		UserID:      uuid.NullUUID{UUID: chirp.UserID, Valid: true},
+		Reason:      parsedReqBody.Reason,
+	})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+
+	actionBytes, _ := json.Marshal(FromDatabaseModerationAction(auditEntry))
+	RespondWithJSON(out, 200, actionBytes)
+}
+
+// HandleModerateUser warns, suspends or lifts the suspension of a user.
+// Warnings and suspensions are also sent to the user by email.
+func (cfg *ApiConfig) HandleModerateUser(out http.ResponseWriter, req *http.Request) {
+	type moderateUserBody struct {
+		Action        string `json:"action"`
+		Reason        string `json:"reason"`
+		DurationHours int    `json:"duration_hours"`
+	}
+	moderator := requestUser(req)
+	userID, err := uuid.Parse(req.PathValue("userID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid UserID")
+		return
+	}
+	parsedReqBody := moderateUserBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if userID == moderator.ID {
+		RespondWithError(out, 400, "You can't moderate yourself")
+		return
+	}
+	target, err := cfg.DB_Config.Queries.GetUserByID(context.Background(), userID)
+	if err != nil {
+		RespondWithError(out, 404, "User does not exist")
+		return
+	}
+	if auth.HasRole(target.Role, auth.RoleModerator) && !auth.HasRole(moderator.Role, auth.RoleAdmin) {
+		RespondWithError(out, 403, "Only admins can moderate moderators")
+		return
+	}
+
+	auditParams := database.CreateModerationActionParams{
+		ModeratorID: moderator.ID,
+		UserID:      uuid.NullUUID{UUID: target.ID, Valid: true},
+		Reason:      parsedReqBody.Reason,
+	}
+	switch parsedReqBody.Action {
+	case "warn":
+		if strings.TrimSpace(parsedReqBody.Reason) == "" {
+			RespondWithError(out, 400, "Reason is required for warnings")
+			return
+		}
+		auditParams.Action = actionWarnUser
+	case "suspend":
+		if parsedReqBody.DurationHours < 1 || parsedReqBody.DurationHours > maxSuspensionHours {
+			RespondWithError(out, 400, fmt.Sprintf("duration_hours must be between 1 and %d", maxSuspensionHours))
+			return
+		}
+		auditParams.Action = actionSuspendUser
+		auditParams.SuspendedUntil = sql.NullTime{Time: time.Now().UTC().Add(time.Duration(parsedReqBody.DurationHours) * time.Hour), Valid: true}
+	case "unsuspend":
+		auditParams.Action = actionUnsuspendUser
+	default:
+		RespondWithError(out, 400, "Action must be warn, suspend or unsuspend")
+		return
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	if auditParams.Action != actionWarnUser {
+		if _, err := queries.SetUserSuspendedUntil(context.Background(), database.SetUserSuspendedUntilParams{SuspendedUntil: auditParams.SuspendedUntil, ID: target.ID}); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+	}
+	auditEntry, err := queries.CreateModerationAction(context.Background(), auditParams)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+
+	switch auditEntry.Action {
+	case actionWarnUser:
+		cfg.sendMail(mail.Message{
+			To:      target.Email,
+			Subject: "A warning about your Chirpy account",
+			Body:    "A moderator warned you about your activity on Chirpy:\n\n" + auditEntry.Reason + "\n",
+		})
+	case actionSuspendUser:
+		cfg.sendMail(mail.Message{
+			To:      target.Email,
+			Subject: "Your Chirpy account is suspended",
+			Body: "A moderator suspended your account until " + auditEntry.SuspendedUntil.Time.Format(time.RFC1123) + ". " +
+				"Until then you can't post, like, follow or report.\n\n" + auditEntry.Reason + "\n",
+		})
+	}
+
+	actionBytes, _ := json.Marshal(FromDatabaseModerationAction(auditEntry))
+	RespondWithJSON(out, 200, actionBytes)
+}
+
+// HandleGetModerationActions lists the audit trail, newest first, optionally
+// only the actions about one user or chirp.
+func (cfg *ApiConfig) HandleGetModerationActions(out http.ResponseWriter, req *http.Request) {
+	params := database.GetModerationActionsParams{}
+	for name, target := range map[string]*uuid.NullUUID{"user_id": &params.UserID, "chirp_id": &params.ChirpID} {
+		value := req.URL.Query().Get(name)
+		if value == "" {
+			continue
+		}
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			RespondWithError(out, 400, name+" must be a valid ID")
+			return
+		}
+		*target = uuid.NullUUID{UUID: parsed, Valid: true}
+	}
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	params.CursorCreatedAt = page.cursorCreatedAt()
+	params.CursorID = page.cursorID()
+	params.PageSize = page.queryLimit()
+
+	actions, err := cfg.DB_Config.Queries.GetModerationActions(context.Background(), params)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	actions, nextCursor := paginate(actions, page, func(action database.ModerationAction) pageCursor {
+		return pageCursor{CreatedAt: action.CreatedAt, ID: action.ID}
+	})
+
+	mappedActions := make([]ModerationAction, len(actions))
+	for ix, action := range actions {
+		mappedActions[ix] = FromDatabaseModerationAction(action)
+	}
+	actionsBytes, _ := json.Marshal(ModerationActionPage{Actions: mappedActions, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, actionsBytes)
+}
+
+// decodeOptionalBody decodes a JSON body into target, leaving it untouched
+// when the request has no body.
+func decodeOptionalBody(req *http.Request, target any) error {
+	err := json.NewDecoder(req.Body).Decode(target)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,68 @@
+package api
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+func TestRespondIfSuspended(t *testing.T) {
+	cases := map[string]struct {
+		usr       database.User
+		suspended bool
+	}{
+		"never suspended":   {database.User{}, false},
+		"suspension over":   {database.User{SuspendedUntil: sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}}, false},
+		"currently blocked": {database.User{SuspendedUntil: sql.NullTime{Time: time.Now().Add(time.Hour), Valid: true}}, true},
+	}
+	for name, tc := range cases {
+		recorder := httptest.NewRecorder()
+		if suspended := respondIfSuspended(recorder, tc.usr); suspended != tc.suspended {
+			t.Errorf("%v: respondIfSuspended should be %v, but got %v", name, tc.suspended, suspended)
+		}
+		if tc.suspended && recorder.Code != 403 {
+			t.Errorf("%v: suspended users should get 403, but got %d", name, recorder.Code)
+		}
+	}
+}
+
+func TestDecodeOptionalBody(t *testing.T) {
+	type body struct {
+		Reason string `json:"reason"`
+	}
+	parsed := body{}
+	if err := decodeOptionalBody(httptest.NewRequest("POST", "/", strings.NewReader("")), &parsed); err != nil || parsed.Reason != "" {
+		t.Errorf("An empty body should be accepted, but got %v, %+v", err, parsed)
+	}
+	if err := decodeOptionalBody(httptest.NewRequest("POST", "/", strings.NewReader(`{"reason": "duplicate"}`)), &parsed); err != nil || parsed.Reason != "duplicate" {
+		t.Errorf("A JSON body should be decoded, but got %v, %+v", err, parsed)
+	}
+	if err := decodeOptionalBody(httptest.NewRequest("POST", "/", strings.NewReader("{")), &parsed); err == nil {
+		t.Errorf("A malformed body should be rejected")
+	}
+}
+
+func TestRespondIfNotEditable(t *testing.T) {
+	owner := uuid.New()
+	cases := map[string]struct {
+		chirp database.Chirp
+		code  int
+	}{
+		"own chirp":       {database.Chirp{UserID: owner}, 0},
+		"someone else's":  {database.Chirp{UserID: uuid.New()}, 403},
+		"deleted":         {database.Chirp{UserID: owner, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}}, 404},
+		"hidden by a mod": {database.Chirp{UserID: owner, HiddenAt: sql.NullTime{Time: time.Now(), Valid: true}}, 404},
+	}
+	for name, tc := range cases {
+		recorder := httptest.NewRecorder()
+		rejected := respondIfNotEditable(recorder, tc.chirp, owner)
+		if rejected != (tc.code != 0) || (rejected && recorder.Code != tc.code) {
+			t.Errorf("%v: expected status %d, but got rejected %v with %d", name, tc.code, rejected, recorder.Code)
+		}
+	}
+}
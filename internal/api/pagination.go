@@ -0,0 +1,137 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageCursor is the position of the last item of a page. It is handed to
+// clients as an opaque base64 string, so its fields can change freely.
+type pageCursor struct {
+	CreatedAt time.Time `json:"c"`
+	ID        uuid.UUID `json:"i"`
+	Rank      *float64  `json:"r,omitempty"`
+}
+
+type pageParams struct {
+	Size   int32
+	Cursor *pageCursor
+}
+
+func encodeCursor(cursor pageCursor) string {
+	cursor.CreatedAt = cursor.CreatedAt.UTC()
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(encoded string) (*pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("Invalid cursor")
+	}
+	cursor := pageCursor{}
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.ID == uuid.Nil {
+		return nil, errors.New("Invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// parsePageParams reads the page size and cursor of a list ordered by
+// creation time.
+func parsePageParams(req *http.Request) (pageParams, error) {
+	return parsePage(req, false)
+}
+
+// parseRankedPageParams reads the page size and cursor of a list ordered by
+// rank, like search results, whose cursors carry the rank.
+func parseRankedPageParams(req *http.Request) (pageParams, error) {
+	return parsePage(req, true)
+}
+
+func parsePage(req *http.Request, ranked bool) (pageParams, error) {
+	params := pageParams{Size: defaultPageSize}
+
+	if limitQuery := req.URL.Query().Get("limit"); limitQuery != "" {
+		limit, err := strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxPageSize {
+			return pageParams{}, errors.New("limit must be a number between 1 and " + strconv.Itoa(maxPageSize))
+		}
+		params.Size = int32(limit)
+	}
+	if cursorQuery := req.URL.Query().Get("cursor"); cursorQuery != "" {
+		cursor, err := decodeCursor(cursorQuery)
+		if err != nil {
+			return pageParams{}, err
+		}
+		// A cursor of another list would silently restart or skip pages.
+		if (cursor.Rank != nil) != ranked {
+			return pageParams{}, errors.New("Invalid cursor")
+		}
+		params.Cursor = cursor
+	}
+	return params, nil
+}
+
+// queryLimit asks the database for one row more than the page size, so the
+// handler can tell whether there is a next page without a COUNT query.
+func (params pageParams) queryLimit() int32 {
+	return params.Size + 1
+}
+
+func (params pageParams) cursorCreatedAt() sql.NullTime {
+	if params.Cursor == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: params.Cursor.CreatedAt, Valid: true}
+}
+
+func (params pageParams) cursorID() uuid.NullUUID {
+	if params.Cursor == nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: params.Cursor.ID, Valid: true}
+}
+
+func (params pageParams) cursorRank() sql.NullFloat64 {
+	if params.Cursor == nil || params.Cursor.Rank == nil {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: *params.Cursor.Rank, Valid: true}
+}
+
+// parseTimeQuery reads an optional RFC 3339 timestamp or YYYY-MM-DD date
+// from the query string. Dates are interpreted as midnight UTC.
+func parseTimeQuery(req *http.Request, name string) (sql.NullTime, error) {
+	value := req.URL.Query().Get(name)
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return sql.NullTime{Time: parsed.UTC(), Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, errors.New(name + " must be an RFC 3339 timestamp or a YYYY-MM-DD date")
+}
+
+// paginate drops the extra row fetched by queryLimit and returns the cursor
+// pointing at the last item of the page, or "" when this is the last page.
+func paginate[T any](items []T, params pageParams, position func(T) pageCursor) ([]T, string) {
+	if len(items) <= int(params.Size) {
+		return items, ""
+	}
+	items = items[:params.Size]
+	return items, encodeCursor(position(items[len(items)-1]))
+}
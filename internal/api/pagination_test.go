@@ -0,0 +1,78 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 1, 12, 30, 0, 123456000, time.UTC)
+	id := uuid.New()
+
+	cursor, err := decodeCursor(encodeCursor(pageCursor{CreatedAt: createdAt, ID: id}))
+	if err != nil {
+		t.Fatalf("Cursor produced by encodeCursor should decode, but got error: %v", err)
+	}
+	if !cursor.CreatedAt.Equal(createdAt) || cursor.ID != id || cursor.Rank != nil {
+		t.Errorf("Decoded cursor %v/%v should be equal to %v/%v", cursor.CreatedAt, cursor.ID, createdAt, id)
+	}
+}
+
+func TestRankedCursorRoundTrip(t *testing.T) {
+	rank := 0.0607927
+	cursor, err := decodeCursor(encodeCursor(pageCursor{CreatedAt: time.Now(), ID: uuid.New(), Rank: &rank}))
+	if err != nil {
+		t.Fatalf("Ranked cursor should decode, but got error: %v", err)
+	}
+	if cursor.Rank == nil || *cursor.Rank != rank {
+		t.Errorf("Decoded rank %v should be equal to %v", cursor.Rank, rank)
+	}
+}
+
+func TestParsePageParamsRejectsInvalidInput(t *testing.T) {
+	for _, query := range []string{"limit=0", "limit=101", "limit=abc", "cursor=not-a-cursor"} {
+		req := httptest.NewRequest("GET", "/api/chirps?"+query, nil)
+		if _, err := parsePageParams(req); err == nil {
+			t.Errorf("parsePageParams should reject %q", query)
+		}
+	}
+}
+
+func TestPageParamsRejectCursorsOfOtherLists(t *testing.T) {
+	rank := 0.5
+	listCursor := encodeCursor(pageCursor{CreatedAt: time.Now(), ID: uuid.New()})
+	searchCursor := encodeCursor(pageCursor{CreatedAt: time.Now(), ID: uuid.New(), Rank: &rank})
+
+	if _, err := parseRankedPageParams(httptest.NewRequest("GET", "/api/chirps/search?q=a&cursor="+listCursor, nil)); err == nil {
+		t.Errorf("Search should reject a cursor without rank")
+	}
+	if _, err := parsePageParams(httptest.NewRequest("GET", "/api/chirps?cursor="+searchCursor, nil)); err == nil {
+		t.Errorf("Lists should reject a search cursor")
+	}
+	if page, err := parseRankedPageParams(httptest.NewRequest("GET", "/api/chirps/search?q=a&cursor="+searchCursor, nil)); err != nil || !page.cursorRank().Valid {
+		t.Errorf("Search should accept its own cursor, got %v", err)
+	}
+	if _, err := parsePageParams(httptest.NewRequest("GET", "/api/chirps?cursor="+listCursor, nil)); err != nil {
+		t.Errorf("Lists should accept their own cursor, got %v", err)
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3}
+	position := func(i int) pageCursor {
+		return pageCursor{CreatedAt: time.Unix(int64(i), 0), ID: uuid.New()}
+	}
+
+	page, next := paginate(items, pageParams{Size: 3}, position)
+	if len(page) != 3 || next != "" {
+		t.Errorf("Last page should keep all items and have no cursor, got %v and %q", page, next)
+	}
+
+	page, next = paginate(items, pageParams{Size: 2}, position)
+	if len(page) != 2 || next == "" {
+		t.Errorf("Page with extra row should be trimmed and have a cursor, got %v and %q", page, next)
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+	"github.com/widua/go-http-server/internal/mail"
+)
+
+// HandleForgotPassword always answers 202, whether the email belongs to an
+// account or not, so the endpoint can't be used to find out who is
+// registered.
+func (cfg *ApiConfig) HandleForgotPassword(out http.ResponseWriter, req *http.Request) {
+	type forgotBody struct {
+		Email string `json:"email"`
+	}
+	parsedReqBody := forgotBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if parsedReqBody.Email == "" {
+		RespondWithError(out, 400, "Email is required")
+		return
+	}
+
+	usr, err := cfg.DB_Config.Queries.GetUserByEmail(context.Background(), parsedReqBody.Email)
+	if err == nil {
+		if err := cfg.sendPasswordReset(usr); err != nil {
+			log.Printf("Error while creating password reset for %v: %v", usr.ID, err)
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error while looking up user for password reset: %v", err)
+	}
+	RespondNoContent(out, 202)
+}
+
+func (cfg *ApiConfig) sendPasswordReset(usr database.User) error {
+	resetToken, err := auth.MakeOneTimeToken()
+	if err != nil {
+		return err
+	}
+	if err := cfg.DB_Config.Queries.CreatePasswordResetToken(context.Background(), database.CreatePasswordResetTokenParams{TokenHash: auth.HashToken(resetToken), UserID: usr.ID}); err != nil {
+		return err
+	}
+
+	cfg.sendMail(mail.Message{
+		To:      usr.Email,
+		Subject: "Reset your Chirpy password",
+		Body: "Someone asked to reset the password of your Chirpy account.\n\n" +
+			"Use this token with POST /api/password/reset within the next hour:\n\n" +
+			resetToken + "\n\n" +
+			"If it wasn't you, you can ignore this email.\n",
+	})
+	return nil
+}
+
+// HandleResetPassword sets a new password with a token from
+// HandleForgotPassword. Every session of the user is revoked, so whoever
+// knew the old password is logged out.
+func (cfg *ApiConfig) HandleResetPassword(out http.ResponseWriter, req *http.Request) {
+	type resetBody struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	parsedReqBody := resetBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if parsedReqBody.Token == "" || parsedReqBody.Password == "" {
+		RespondWithError(out, 400, "Token and password are required")
+		return
+	}
+	passwdHash, err := auth.HashPassword(parsedReqBody.Password)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	userID, err := queries.UsePasswordResetToken(context.Background(), auth.HashToken(parsedReqBody.Token))
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(out, 401, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := queries.UpdateUserPassword(context.Background(), database.UpdateUserPasswordParams{HashedPassword: passwdHash, ID: userID}); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := queries.InvalidatePasswordResetTokens(context.Background(), userID); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := queries.RevokeAllUserRefreshTokens(context.Background(), userID); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
@@ -0,0 +1,213 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+const (
+	minHandleLength      = 3
+	maxHandleLength      = 30
+	maxDisplayNameLength = 50
+	maxBioLength         = 160
+	maxAvatarURLLength   = 2048
+	// handleIndex is the unique index on lower(handle).
+	handleIndex = "idx_users_handle"
+	// maxGeneratedHandleAttempts is how often signup retries when the
+	// handle generated for a user is already taken.
+	maxGeneratedHandleAttempts = 5
+)
+
+// reservedHandles can't be taken by users, either because they would shadow
+// routes like /api/users/verify or because they would impersonate Chirpy.
+var reservedHandles = map[string]bool{
+	"me": true, "verify": true, "mfa": true, "settings": true,
+	"admin": true, "administrator": true, "moderator": true, "mod": true, "root": true, "system": true,
+	"chirpy": true, "support": true, "help": true, "security": true, "api": true,
+	"null": true, "undefined": true,
+}
+
+// normalizeHandle strips the "@" users tend to type in front of a handle.
+func normalizeHandle(handle string) string {
+	return strings.TrimPrefix(handle, "@")
+}
+
+// validateHandle accepts 3 to 30 ASCII letters, digits and underscores that
+// aren't reserved. Handles are unique regardless of case.
+func validateHandle(handle string) error {
+	if len(handle) < minHandleLength || len(handle) > maxHandleLength {
+		return fmt.Errorf("Handle must be between %d and %d characters long", minHandleLength, maxHandleLength)
+	}
+	for _, r := range handle {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_') {
+			return errors.New("Handle can only contain letters, digits and underscores")
+		}
+	}
+	if reservedHandles[strings.ToLower(handle)] {
+		return errors.New("Handle is reserved")
+	}
+	return nil
+}
+
+func validateProfileText(name string, value string, maxLength int, allowLineBreaks bool) error {
+	if length := utf8.RuneCountInString(value); length > maxLength {
+		return fmt.Errorf("%v can be at most %d characters long", name, maxLength)
+	}
+	for _, r := range value {
+		if unicode.IsControl(r) && !(allowLineBreaks && r == '\n') {
+			return fmt.Errorf("%v can't contain control characters", name)
+		}
+	}
+	return nil
+}
+
+// validateAvatarURL accepts an empty value, which removes the avatar, or an
+// absolute http(s) URL.
+func validateAvatarURL(avatarURL string) error {
+	if avatarURL == "" {
+		return nil
+	}
+	parsed, err := url.Parse(avatarURL)
+	if err != nil || len(avatarURL) > maxAvatarURLLength || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return errors.New("Avatar URL must be an http or https URL")
+	}
+	return nil
+}
+
+// HandleGetProfile returns the public profile of a user, looked up by ID or
+// by handle, with or without a leading "@".
+func (cfg *ApiConfig) HandleGetProfile(out http.ResponseWriter, req *http.Request) {
+	handleOrID := req.PathValue("handleOrID")
+
+	var usr database.User
+	var err error
+	if userId, parseErr := uuid.Parse(handleOrID); parseErr == nil {
+		usr, err = cfg.DB_Config.Queries.GetUserByID(context.Background(), userId)
+	} else {
+		usr, err = cfg.DB_Config.Queries.GetUserByHandle(context.Background(), normalizeHandle(handleOrID))
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(out, 404, "User does not exist")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	stats, err := cfg.DB_Config.Queries.GetUserProfileStats(context.Background(), usr.ID)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	profileBytes, _ := json.Marshal(FromDatabaseProfile(usr, stats))
+	RespondWithJSON(out, 200, profileBytes)
+}
+
+// HandleUpdateProfile changes the caller's public profile. Fields left out
+// of the body keep their value.
+func (cfg *ApiConfig) HandleUpdateProfile(out http.ResponseWriter, req *http.Request) {
+	type profileBody struct {
+		Handle      *string `json:"handle"`
+		DisplayName *string `json:"display_name"`
+		Bio         *string `json:"bio"`
+		AvatarURL   *string `json:"avatar_url"`
+	}
+	usr := requestUser(req)
+	parsedReqBody := profileBody{}
+	decoder := json.NewDecoder(req.Body)
+	if err := decoder.Decode(&parsedReqBody); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	params := database.UpdateUserProfileParams{Handle: usr.Handle, DisplayName: usr.DisplayName, Bio: usr.Bio, AvatarUrl: usr.AvatarUrl, ID: usr.ID}
+	if parsedReqBody.Handle != nil {
+		params.Handle = normalizeHandle(*parsedReqBody.Handle)
+		if err := validateHandle(params.Handle); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+	}
+	if parsedReqBody.DisplayName != nil {
+		params.DisplayName = strings.TrimSpace(normalizeChirp(*parsedReqBody.DisplayName))
+		if err := validateProfileText("Display name", params.DisplayName, maxDisplayNameLength, false); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+	}
+	if parsedReqBody.Bio != nil {
+		params.Bio = strings.TrimSpace(normalizeChirp(*parsedReqBody.Bio))
+		if err := validateProfileText("Bio", params.Bio, maxBioLength, true); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+	}
+	if parsedReqBody.AvatarURL != nil {
+		params.AvatarUrl = strings.TrimSpace(*parsedReqBody.AvatarURL)
+		if err := validateAvatarURL(params.AvatarUrl); err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+	}
+
+	updatedUser, err := cfg.DB_Config.Queries.UpdateUserProfile(context.Background(), params)
+	if database.IsUniqueViolation(err, handleIndex) {
+		RespondWithError(out, 409, "Handle is already taken")
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	stats, err := cfg.DB_Config.Queries.GetUserProfileStats(context.Background(), updatedUser.ID)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	profileBytes, _ := json.Marshal(FromDatabaseProfile(updatedUser, stats))
+	RespondWithJSON(out, 200, profileBytes)
+}
+
+// chirpAuthors loads the public names of the given users with one query.
+func (cfg *ApiConfig) chirpAuthors(userIDs []uuid.UUID) (map[uuid.UUID]ChirpAuthor, error) {
+	authors := make(map[uuid.UUID]ChirpAuthor, len(userIDs))
+	if len(userIDs) == 0 {
+		return authors, nil
+	}
+	rows, err := cfg.DB_Config.Queries.GetChirpAuthors(context.Background(), userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		authors[row.ID] = ChirpAuthor{Handle: row.Handle, DisplayName: row.DisplayName}
+	}
+	return authors, nil
+}
+
+// addAuthors fills the author of a page of chirps.
+func (cfg *ApiConfig) addAuthors(chirps []Chirp) error {
+	userIDs := make([]uuid.UUID, len(chirps))
+	for ix, chirp := range chirps {
+		userIDs[ix] = chirp.UserID
+	}
+	authors, err := cfg.chirpAuthors(userIDs)
+	if err != nil {
+		return err
+	}
+	for ix := range chirps {
+		chirps[ix].Author = authors[chirps[ix].UserID]
+	}
+	return nil
+}
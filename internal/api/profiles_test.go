@@ -0,0 +1,67 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidateHandle(t *testing.T) {
+	valid := []string{"chirper", "Chirp_Fan_42", "abc", "a23456789012345678901234567890"}
+	for _, handle := range valid {
+		if err := validateHandle(handle); err != nil {
+			t.Errorf("%q should be a valid handle, but got %v", handle, err)
+		}
+	}
+	invalid := []string{"ab", "a234567890123456789012345678901", "with space", "dash-ed", "żółw", "@chirper", "me", "Admin", "VERIFY"}
+	for _, handle := range invalid {
+		if err := validateHandle(handle); err == nil {
+			t.Errorf("%q should be rejected as a handle", handle)
+		}
+	}
+	if normalizeHandle("@chirper") != "chirper" {
+		t.Errorf("Leading @ should be stripped from handles")
+	}
+}
+
+func TestValidateProfileFields(t *testing.T) {
+	if err := validateProfileText("Bio", "first line\nsecond line", maxBioLength, true); err != nil {
+		t.Errorf("Bios should allow line breaks, but got %v", err)
+	}
+	if err := validateProfileText("Display name", "two\nlines", maxDisplayNameLength, false); err == nil {
+		t.Errorf("Display names should reject line breaks")
+	}
+	if err := validateProfileText("Display name", string(make([]rune, maxDisplayNameLength+1)), maxDisplayNameLength, false); err == nil {
+		t.Errorf("Display names longer than %d characters should be rejected", maxDisplayNameLength)
+	}
+
+	for _, avatarURL := range []string{"", "https://example.com/me.png", "http://cdn.example.com/a?size=64"} {
+		if err := validateAvatarURL(avatarURL); err != nil {
+			t.Errorf("%q should be a valid avatar URL, but got %v", avatarURL, err)
+		}
+	}
+	for _, avatarURL := range []string{"javascript:alert(1)", "/relative.png", "ftp://example.com/a.png", "https://"} {
+		if err := validateAvatarURL(avatarURL); err == nil {
+			t.Errorf("%q should be rejected as an avatar URL", avatarURL)
+		}
+	}
+}
+
+func TestSetThreadAuthors(t *testing.T) {
+	rootAuthor, replyAuthor := uuid.New(), uuid.New()
+	thread := ThreadNode{
+		Chirp: Chirp{UserID: rootAuthor},
+		Replies: []ThreadNode{
+			{Chirp: Chirp{UserID: replyAuthor}, Replies: []ThreadNode{{Chirp: Chirp{UserID: rootAuthor}}}},
+		},
+	}
+	authors := map[uuid.UUID]ChirpAuthor{
+		rootAuthor:  {Handle: "root", DisplayName: "Root"},
+		replyAuthor: {Handle: "reply"},
+	}
+
+	setThreadAuthors(&thread, authors)
+	if thread.Author.Handle != "root" || thread.Replies[0].Author.Handle != "reply" || thread.Replies[0].Replies[0].Author.Handle != "root" {
+		t.Errorf("Every chirp in the thread should get its author, but got %+v", thread)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/widua/go-http-server/internal/database"
+	"github.com/widua/go-http-server/internal/entitlements"
+)
+
+// validatePublishAt checks the requested publication time of a scheduled
+// chirp. A missing time means the chirp is published right away.
+func validatePublishAt(publishAt *time.Time, plan entitlements.Plan, now time.Time) (sql.NullTime, error) {
+	if publishAt == nil {
+		return sql.NullTime{}, nil
+	}
+	if !publishAt.After(now) {
+		return sql.NullTime{}, errors.New("publish_at must be in the future")
+	}
+	if publishAt.After(now.AddDate(0, 0, plan.MaxScheduleDays)) {
+		return sql.NullTime{}, fmt.Errorf("publish_at can be at most %d days ahead", plan.MaxScheduleDays)
+	}
+	return sql.NullTime{Time: publishAt.UTC(), Valid: true}, nil
+}
+
+// HandleGetScheduledChirps lists the caller's chirps that are not published
+// yet, the next one to be published first.
+func (cfg *ApiConfig) HandleGetScheduledChirps(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	chirps, err := cfg.DB_Config.Queries.GetScheduledChirps(context.Background(), database.GetScheduledChirpsParams{UserID: userId, CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	chirps, nextCursor := paginate(chirps, page, func(chirp database.Chirp) pageCursor {
+		return pageCursor{CreatedAt: chirp.CreatedAt, ID: chirp.ID}
+	})
+
+	mappedChirps := make([]Chirp, len(chirps))
+	for ix, chirp := range chirps {
+		mappedChirps[ix] = FromDatabaseChirp(chirp)
+	}
+	if err := cfg.addAuthors(mappedChirps); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	chirpsBytes, _ := json.Marshal(ChirpPage{Chirps: mappedChirps, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, chirpsBytes)
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/widua/go-http-server/internal/censor"
+	"github.com/widua/go-http-server/internal/entitlements"
+)
+
+func TestValidatePublishAt(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	plan := entitlements.Plan{MaxScheduleDays: 30}
+	at := func(offset time.Duration) *time.Time {
+		publishAt := now.Add(offset)
+		return &publishAt
+	}
+
+	if publishAt, err := validatePublishAt(nil, plan, now); err != nil || publishAt.Valid {
+		t.Errorf("Missing publish_at should publish right away, but got %v, %v", publishAt, err)
+	}
+	if publishAt, err := validatePublishAt(at(time.Hour), plan, now); err != nil || !publishAt.Time.Equal(now.Add(time.Hour)) {
+		t.Errorf("publish_at an hour ahead should be accepted, but got %v, %v", publishAt, err)
+	}
+	if _, err := validatePublishAt(at(0), plan, now); err == nil {
+		t.Errorf("publish_at in the present should be rejected")
+	}
+	if _, err := validatePublishAt(at(31*24*time.Hour), plan, now); err == nil {
+		t.Errorf("publish_at past MaxScheduleDays should be rejected")
+	}
+}
+
+func TestValidateChirpLengthFollowsPlan(t *testing.T) {
+	chirpCensor, _ := censor.New(nil)
+	cfg := &ApiConfig{Censor: chirpCensor}
+	plans := entitlements.Default()
+	body := strings.Repeat("ż", 200)
+
+	if _, err := cfg.ValidateChirp(body, plans.Free); err == nil {
+		t.Errorf("200 characters should be too long for the free plan")
+	}
+	if _, err := cfg.ValidateChirp(body, plans.Red); err != nil {
+		t.Errorf("200 characters should fit the Red plan, but got %v", err)
+	}
+	if _, err := cfg.ValidateChirp(strings.Repeat("ż", 140), plans.Free); err != nil {
+		t.Errorf("Length should be counted in characters, not bytes, but got %v", err)
+	}
+}
@@ -0,0 +1,11 @@
+package api
+
+import "testing"
+
+func TestHighlightSnippetEscapesBody(t *testing.T) {
+	snippet := "<img src=x onerror=alert(1)> \x01kerfuffle\x02 & <script>"
+	expected := "&lt;img src=x onerror=alert(1)&gt; <mark>kerfuffle</mark> &amp; &lt;script&gt;"
+	if highlighted := highlightSnippet(snippet); highlighted != expected {
+		t.Errorf("Snippet should be escaped with only matches marked, expected %q, got %q", expected, highlighted)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+const maxUserAgentLength = 512
+
+// HandleGetSessions lists the caller's active sessions. A session is a
+// refresh token family: it starts at login and keeps its ID while the
+// refresh token is rotated.
+func (cfg *ApiConfig) HandleGetSessions(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+
+	sessions, err := cfg.DB_Config.Queries.GetActiveSessionsByUserID(context.Background(), userId)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	mappedSessions := make([]Session, len(sessions))
+	for ix, session := range sessions {
+		mappedSessions[ix] = Session{
+			ID:         session.FamilyID,
+			CreatedAt:  session.SessionCreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+		}
+	}
+
+	sessionsBytes, _ := json.Marshal(mappedSessions)
+	RespondWithJSON(out, 200, sessionsBytes)
+}
+
+func (cfg *ApiConfig) HandleDeleteSession(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+	sessionID, err := uuid.Parse(req.PathValue("sessionID"))
+	if err != nil {
+		RespondWithError(out, 404, "Invalid SessionID")
+		return
+	}
+
+	revoked, err := cfg.DB_Config.Queries.RevokeSession(context.Background(), database.RevokeSessionParams{FamilyID: sessionID, UserID: userId})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if revoked == 0 {
+		RespondWithError(out, 404, "Session does not exist")
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func (cfg *ApiConfig) HandleDeleteAllSessions(out http.ResponseWriter, req *http.Request) {
+	userId := requestUser(req).ID
+
+	if err := cfg.DB_Config.Queries.RevokeAllUserRefreshTokens(context.Background(), userId); err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func userAgent(req *http.Request) string {
+	agent := req.UserAgent()
+	if len(agent) > maxUserAgentLength {
+		agent = strings.ToValidUTF8(agent[:maxUserAgentLength], "")
+	}
+	return agent
+}
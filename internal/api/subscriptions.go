@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+const (
+	subscriptionCanceled = "canceled"
+	subscriptionExpired  = "expired"
+	// subscriptionNone is reported for users who never subscribed.
+	subscriptionNone = "none"
+)
+
+func (cfg *ApiConfig) HandleGetSubscription(out http.ResponseWriter, req *http.Request) {
+	usr := requestUser(req)
+	subscription := Subscription{Status: subscriptionNone, IsChirpyRed: usr.IsChirpyRed}
+
+	stored, err := cfg.DB_Config.Queries.GetSubscriptionByUserID(context.Background(), usr.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err == nil {
+		subscription = FromDatabaseSubscription(stored, usr.IsChirpyRed)
+	}
+
+	subscriptionBytes, _ := json.Marshal(subscription)
+	RespondWithJSON(out, 200, subscriptionBytes)
+}
+
+// RunSubscriptionSweeper takes Chirpy Red away from users whose paid period
+// ended without a renewal, every interval until ctx is done. Polka normally
+// sends subscription.expired, but the sweeper doesn't depend on it arriving.
+func (cfg *ApiConfig) RunSubscriptionSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		expired, err := cfg.DB_Config.Queries.ExpireSubscriptions(ctx)
+		if err != nil {
+			log.Printf("Error while expiring subscriptions: %v", err)
+		} else if expired > 0 {
+			log.Printf("Expired Chirpy Red for %d users", expired)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+const (
+	maxThreadDepth   = 50
+	maxThreadReplies = 500
+)
+
+type ThreadNode struct {
+	Chirp
+	ReplyCount int64        `json:"reply_count"`
+	Replies    []ThreadNode `json:"replies"`
+}
+
+type ChirpThread struct {
+	Root      Chirp   `json:"root"`
+	Ancestors []Chirp `json:"ancestors"`
+	// AncestorsTruncated is set when the chirp is nested deeper than
+	// maxThreadDepth, so Ancestors doesn't reach up to Root.
+	AncestorsTruncated bool       `json:"ancestors_truncated"`
+	Chirp              ThreadNode `json:"chirp"`
+}
+
+func (cfg *ApiConfig) HandleGetChirpThread(out http.ResponseWriter, req *http.Request) {
+	chirpID, err := uuid.Parse(req.PathValue("chirpID"))
+	if err != nil {
+		RespondWithError(out, 400, "It's not valid chirp ID")
+		return
+	}
+	chirp, err := cfg.DB_Config.Queries.GetVisibleChirpByID(context.Background(), chirpID)
+	if err != nil {
+		RespondWithError(out, 404, "Chirp does not exist")
+		return
+	}
+
+	// Replies of users the viewer blocked or muted are left out, together
+	// with the replies below them.
+	viewer := viewerID(req)
+	ancestors, err := cfg.DB_Config.Queries.GetChirpAncestors(context.Background(), database.GetChirpAncestorsParams{ChirpID: chirp.ID, MaxDepth: maxThreadDepth})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	replies, err := cfg.DB_Config.Queries.GetChirpReplies(context.Background(), database.GetChirpRepliesParams{ChirpID: chirp.ID, ViewerID: viewer, MaxDepth: maxThreadDepth, MaxReplies: maxThreadReplies})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	replyCount, err := cfg.DB_Config.Queries.CountVisibleChirpReplies(context.Background(), database.CountVisibleChirpRepliesParams{ParentID: uuid.NullUUID{UUID: chirp.ID, Valid: true}, ViewerID: viewer})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	thread := ChirpThread{
+		Ancestors: make([]Chirp, len(ancestors)),
+		Chirp:     buildReplyTree(FromDatabaseChirp(chirp), replyCount, replies),
+	}
+	for ix, ancestor := range ancestors {
+		thread.Ancestors[ix] = Chirp{ID: ancestor.ID, CreatedAt: ancestor.CreatedAt, UpdatedAt: ancestor.UpdatedAt, Body: ancestor.Body, UserID: ancestor.UserID, ParentID: ancestor.ParentID, Edited: ancestor.EditedAt.Valid, Deleted: ancestor.DeletedAt.Valid, Hidden: ancestor.HiddenAt.Valid}
+		// Hidden ancestors keep the thread together, but like tombstones
+		// they don't show what was hidden.
+		if ancestor.HiddenAt.Valid {
+			thread.Ancestors[ix].Body = ""
+		}
+	}
+	thread.Root, thread.AncestorsTruncated = threadRoot(thread.Chirp.Chirp, thread.Ancestors)
+	if thread.AncestorsTruncated {
+		root, err := cfg.DB_Config.Queries.GetChirpThreadRoot(context.Background(), chirp.ID)
+		if err != nil {
+			RespondWithError(out, 400, err.Error())
+			return
+		}
+		thread.Root = FromDatabaseChirp(root)
+		if thread.Root.Hidden {
+			thread.Root.Body = ""
+		}
+	}
+
+	authorIDs := []uuid.UUID{chirp.UserID, thread.Root.UserID}
+	for _, ancestor := range ancestors {
+		authorIDs = append(authorIDs, ancestor.UserID)
+	}
+	for _, reply := range replies {
+		authorIDs = append(authorIDs, reply.UserID)
+	}
+	authors, err := cfg.chirpAuthors(authorIDs)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	for ix := range thread.Ancestors {
+		thread.Ancestors[ix].Author = authors[thread.Ancestors[ix].UserID]
+	}
+	thread.Root.Author = authors[thread.Root.UserID]
+	setThreadAuthors(&thread.Chirp, authors)
+
+	threadBytes, _ := json.Marshal(thread)
+	RespondWithJSON(out, 200, threadBytes)
+}
+
+// threadRoot returns the first chirp of a thread from the ancestors of
+// chirp, oldest first. When the ancestors stop short of a chirp without a
+// parent, they were cut off at maxThreadDepth and the root has to be looked
+// up on its own.
+func threadRoot(chirp Chirp, ancestors []Chirp) (Chirp, bool) {
+	if len(ancestors) == 0 {
+		return chirp, false
+	}
+	if ancestors[0].ParentID.Valid {
+		return Chirp{}, true
+	}
+	return ancestors[0], false
+}
+
+// buildReplyTree nests the flat, depth-ordered rows of GetChirpReplies under
+// the chirp they answer. Rows whose parent was cut off by the reply limit are
+// dropped, but still counted in their parent's reply_count.
+func buildReplyTree(root Chirp, rootReplyCount int64, replies []database.GetChirpRepliesRow) ThreadNode {
+	children := make(map[uuid.UUID][]database.GetChirpRepliesRow)
+	for _, reply := range replies {
+		children[reply.ParentID.UUID] = append(children[reply.ParentID.UUID], reply)
+	}
+
+	var build func(chirp Chirp, replyCount int64) ThreadNode
+	build = func(chirp Chirp, replyCount int64) ThreadNode {
+		node := ThreadNode{Chirp: chirp, ReplyCount: replyCount, Replies: []ThreadNode{}}
+		for _, reply := range children[chirp.ID] {
+			replyChirp := Chirp{ID: reply.ID, CreatedAt: reply.CreatedAt, UpdatedAt: reply.UpdatedAt, Body: reply.Body, UserID: reply.UserID, ParentID: reply.ParentID, Edited: reply.EditedAt.Valid, Deleted: reply.DeletedAt.Valid}
+			node.Replies = append(node.Replies, build(replyChirp, reply.ReplyCount))
+		}
+		return node
+	}
+	return build(root, rootReplyCount)
+}
+
+func setThreadAuthors(node *ThreadNode, authors map[uuid.UUID]ChirpAuthor) {
+	node.Author = authors[node.UserID]
+	for ix := range node.Replies {
+		setThreadAuthors(&node.Replies[ix], authors)
+	}
+}
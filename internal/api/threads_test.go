@@ -0,0 +1,53 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+func TestBuildReplyTree(t *testing.T) {
+	root := Chirp{ID: uuid.New()}
+	reply := database.GetChirpRepliesRow{ID: uuid.New(), ParentID: uuid.NullUUID{UUID: root.ID, Valid: true}, ReplyCount: 1}
+	nested := database.GetChirpRepliesRow{ID: uuid.New(), ParentID: uuid.NullUUID{UUID: reply.ID, Valid: true}}
+	sibling := database.GetChirpRepliesRow{ID: uuid.New(), ParentID: uuid.NullUUID{UUID: root.ID, Valid: true}}
+
+	tree := buildReplyTree(root, 2, []database.GetChirpRepliesRow{reply, sibling, nested})
+
+	if tree.ID != root.ID || tree.ReplyCount != 2 {
+		t.Fatalf("Tree root should be %v with 2 replies, got %v with %d", root.ID, tree.ID, tree.ReplyCount)
+	}
+	if len(tree.Replies) != 2 || tree.Replies[0].ID != reply.ID || tree.Replies[1].ID != sibling.ID {
+		t.Fatalf("Root should have direct replies in query order, got %+v", tree.Replies)
+	}
+	if len(tree.Replies[0].Replies) != 1 || tree.Replies[0].Replies[0].ID != nested.ID {
+		t.Errorf("Nested reply should be attached to its parent, got %+v", tree.Replies[0].Replies)
+	}
+	if tree.Replies[1].Replies == nil {
+		t.Errorf("Leaf replies should have an empty, not nil, list of replies")
+	}
+}
+
+func TestThreadRoot(t *testing.T) {
+	chain := make([]Chirp, maxThreadDepth+5)
+	for ix := range chain {
+		chain[ix] = Chirp{ID: uuid.New()}
+		if ix > 0 {
+			chain[ix].ParentID = uuid.NullUUID{UUID: chain[ix-1].ID, Valid: true}
+		}
+	}
+
+	if root, truncated := threadRoot(chain[0], nil); root.ID != chain[0].ID || truncated {
+		t.Errorf("A chirp without ancestors should be its own root, got %v, truncated %v", root.ID, truncated)
+	}
+	if root, truncated := threadRoot(chain[3], chain[:3]); root.ID != chain[0].ID || truncated {
+		t.Errorf("Root should be the oldest ancestor, got %v, truncated %v", root.ID, truncated)
+	}
+
+	// GetChirpAncestors stops maxThreadDepth chirps above the last one.
+	last := len(chain) - 1
+	if _, truncated := threadRoot(chain[last], chain[last-maxThreadDepth:last]); !truncated {
+		t.Errorf("Ancestors of a chirp deeper than %d should be truncated", maxThreadDepth)
+	}
+}
@@ -0,0 +1,79 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	validationEmpty            = "empty"
+	validationTooLong          = "too_long"
+	validationControlCharacter = "control_character"
+)
+
+// ValidationError explains why a chirp was rejected in a form clients can
+// act on without parsing the message.
+type ValidationError struct {
+	Code    string `json:"code"`
+	Message string `json:"error"`
+	// Limit and Length are set for too_long, counted in characters.
+	Limit  int `json:"limit,omitempty"`
+	Length int `json:"length,omitempty"`
+}
+
+func (err *ValidationError) Error() string {
+	return err.Message
+}
+
+// normalizeChirp brings a chirp body into NFC, so "é" counts as one
+// character whether it was sent precomposed or as "e" and a combining accent.
+// Windows line breaks become "\n".
+func normalizeChirp(body string) string {
+	return norm.NFC.String(strings.ReplaceAll(body, "\r\n", "\n"))
+}
+
+// checkChirpText rejects bodies that are empty or only white space, longer
+// than maxLength characters, or contain control characters other than line
+// breaks and tabs. Bidirectional overrides count as control characters, as
+// they can make text display differently from what it says.
+func checkChirpText(body string, maxLength int) error {
+	if strings.TrimSpace(body) == "" {
+		return &ValidationError{Code: validationEmpty, Message: "Chirp can't be empty"}
+	}
+	for _, r := range body {
+		if r == '\n' || r == '\t' {
+			continue
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Bidi_Control, r) {
+			return &ValidationError{Code: validationControlCharacter, Message: fmt.Sprintf("Chirp can't contain control character %U", r)}
+		}
+	}
+	if length := utf8.RuneCountInString(body); length > maxLength {
+		return &ValidationError{
+			Code:    validationTooLong,
+			Message: fmt.Sprintf("Chirp is too long, %d characters of %d allowed", length, maxLength),
+			Limit:   maxLength,
+			Length:  length,
+		}
+	}
+	return nil
+}
+
+// respondWithValidationError sends a ValidationError with all its fields,
+// and any other error like RespondWithError does.
+func respondWithValidationError(out http.ResponseWriter, err error) {
+	validationErr := &ValidationError{}
+	if !errors.As(err, &validationErr) {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	errorBytes, _ := json.Marshal(validationErr)
+	RespondWithJSON(out, 400, errorBytes)
+}
@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/widua/go-http-server/internal/censor"
+	"github.com/widua/go-http-server/internal/entitlements"
+)
+
+func TestValidateChirpCountsCharacters(t *testing.T) {
+	chirpCensor, _ := censor.New(nil)
+	cfg := &ApiConfig{Censor: chirpCensor}
+	plan := entitlements.Plan{MaxChirpLength: 140}
+
+	if _, err := cfg.ValidateChirp(strings.Repeat("😀", 50), plan); err != nil {
+		t.Errorf("50 emoji should fit in 140 characters, but got %v", err)
+	}
+	// "e" followed by a combining acute accent is one character in NFC.
+	decomposed := strings.Repeat("e\u0301", 140)
+	body, err := cfg.ValidateChirp(decomposed, plan)
+	if err != nil {
+		t.Fatalf("140 decomposed accented letters should fit in 140 characters, but got %v", err)
+	}
+	if body != strings.Repeat("\u00e9", 140) {
+		t.Errorf("Chirp should be stored in NFC, but got %q", body)
+	}
+
+	_, err = cfg.ValidateChirp(strings.Repeat("a", 141), plan)
+	validationErr := &ValidationError{}
+	if !errors.As(err, &validationErr) || validationErr.Code != validationTooLong || validationErr.Limit != 140 || validationErr.Length != 141 {
+		t.Errorf("141 characters should be rejected as too_long with limit 140 and length 141, but got %#v", err)
+	}
+}
+
+func TestValidateChirpChecksCensoredLength(t *testing.T) {
+	chirpCensor, _ := censor.New([]censor.Word{{Pattern: "sharbert", Strategy: censor.StrategyReplace, Replacement: "[redacted by the moderators]"}})
+	cfg := &ApiConfig{Censor: chirpCensor}
+	plan := entitlements.Plan{MaxChirpLength: 140}
+
+	// 140 characters before censoring, 160 after.
+	body := strings.Repeat("a", 131) + " sharbert"
+	_, err := cfg.ValidateChirp(body, plan)
+	validationErr := &ValidationError{}
+	if !errors.As(err, &validationErr) || validationErr.Code != validationTooLong || validationErr.Limit != 140 || validationErr.Length != 160 {
+		t.Errorf("A chirp growing past the limit when censored should be rejected as too_long with length 160, but got %#v", err)
+	}
+
+	censored, err := cfg.ValidateChirp(strings.Repeat("a", 111)+" sharbert", plan)
+	if err != nil || censored != strings.Repeat("a", 111)+" [redacted by the moderators]" {
+		t.Errorf("A censored chirp of exactly 140 characters should be allowed, but got %q, %v", censored, err)
+	}
+}
+
+func TestCheckChirpTextRejections(t *testing.T) {
+	cases := map[string]string{
+		"":                  validationEmpty,
+		" \n\t ":            validationEmpty,
+		"null\x00byte":      validationControlCharacter,
+		"bell\a":            validationControlCharacter,
+		"evil\u202etxt.exe": validationControlCharacter,
+		"del\u007f":         validationControlCharacter,
+	}
+	for body, code := range cases {
+		validationErr := &ValidationError{}
+		if err := checkChirpText(body, 140); !errors.As(err, &validationErr) || validationErr.Code != code {
+			t.Errorf("%q should be rejected with code %v, but got %v", body, code, err)
+		}
+	}
+	if err := checkChirpText("line one\nline two\twith a tab 👨‍👩‍👧", 140); err != nil {
+		t.Errorf("Line breaks, tabs and emoji joiners should be allowed, but got %v", err)
+	}
+}
+
+func TestRespondWithValidationError(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	respondWithValidationError(recorder, &ValidationError{Code: validationTooLong, Message: "Chirp is too long", Limit: 140, Length: 150})
+
+	response := map[string]any{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != 400 || response["code"] != validationTooLong || response["error"] != "Chirp is too long" || response["limit"] != 140.0 || response["length"] != 150.0 {
+		t.Errorf("Validation errors should be sent as 400 with code, error, limit and length, but got %v %v", recorder.Code, response)
+	}
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/database"
+)
+
+const (
+	polkaSignatureHeader    = "Polka-Signature"
+	polkaSignatureTolerance = 5 * time.Minute
+	maxWebhookBodySize      = 1 << 20
+)
+
+var errWebhookUserNotFound = errors.New("User does not exist")
+
+type polkaEvent struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  struct {
+		UserID uuid.UUID `json:"user_id"`
+		// ExpiresAt is the end of the paid period. Without it, a period
+		// lasts 30 days.
+		ExpiresAt *time.Time `json:"expires_at"`
+	} `json:"data"`
+}
+
+// HandlePolkaWebhooks verifies the signature over the raw body, stores the
+// event and processes it. Polka redelivers events until it gets a 2xx, so
+// an event that was already processed is acknowledged without running it
+// again.
+func (cfg *ApiConfig) HandlePolkaWebhooks(out http.ResponseWriter, req *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(out, req.Body, maxWebhookBodySize))
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	if err := auth.VerifyWebhookSignature(req.Header.Get(polkaSignatureHeader), body, cfg.POLKA_KEY, time.Now(), polkaSignatureTolerance); err != nil {
+		RespondWithError(out, 401, err.Error())
+		return
+	}
+	event, err := parsePolkaEvent(body)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+
+	err = cfg.DB_Config.Queries.CreateWebhookEvent(context.Background(), database.CreateWebhookEventParams{EventID: event.ID, Event: event.Event, Payload: body})
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	_, err = cfg.processWebhookEvent(event.ID)
+	respondToWebhookProcessing(out, err)
+}
+
+func parsePolkaEvent(body []byte) (polkaEvent, error) {
+	event := polkaEvent{}
+	if err := json.Unmarshal(body, &event); err != nil {
+		return polkaEvent{}, errors.New("Malformed webhook payload")
+	}
+	if event.ID == "" || event.Event == "" {
+		return polkaEvent{}, errors.New("Webhook payload needs id and event")
+	}
+	return event, nil
+}
+
+// processWebhookEvent runs a stored event unless it was processed before,
+// which it reports with alreadyProcessed. The row stays locked while the
+// event runs, so concurrent deliveries of the same event can't both apply
+// it. Failures are recorded on the event for the admin event list.
+func (cfg *ApiConfig) processWebhookEvent(eventID string) (alreadyProcessed bool, err error) {
+	tx, err := cfg.DB_Config.Db_connection.BeginTx(context.Background(), nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	queries := cfg.DB_Config.Queries.WithTx(tx)
+
+	stored, err := queries.GetWebhookEventForUpdate(context.Background(), eventID)
+	if err != nil {
+		return false, err
+	}
+	if stored.ProcessedAt.Valid {
+		return true, nil
+	}
+
+	if applyErr := applyPolkaEvent(queries, stored); applyErr != nil {
+		tx.Rollback()
+		err := cfg.DB_Config.Queries.RecordWebhookEventError(context.Background(), database.RecordWebhookEventErrorParams{ID: stored.ID, LastError: sql.NullString{String: applyErr.Error(), Valid: true}})
+		if err != nil {
+			log.Printf("Error while recording failure of webhook event %v: %v", eventID, err)
+		}
+		return false, applyErr
+	}
+	if err := queries.MarkWebhookEventProcessed(context.Background(), stored.ID); err != nil {
+		return false, err
+	}
+	return false, tx.Commit()
+}
+
+// applyPolkaEvent makes the changes an event asks for. Events Chirpy doesn't
+// care about are accepted and marked processed without doing anything.
+func applyPolkaEvent(queries *database.Queries, stored database.WebhookEvent) error {
+	event, err := parsePolkaEvent(stored.Payload)
+	if err != nil {
+		return err
+	}
+	expiresAt := sql.NullTime{}
+	if event.Data.ExpiresAt != nil {
+		expiresAt = sql.NullTime{Time: event.Data.ExpiresAt.UTC(), Valid: true}
+	}
+
+	switch event.Event {
+	case "user.upgraded":
+		if err := setChirpyRed(queries, event.Data.UserID, true); err != nil {
+			return err
+		}
+		return queries.ActivateSubscription(context.Background(), database.ActivateSubscriptionParams{UserID: event.Data.UserID, ExpiresAt: expiresAt})
+	case "subscription.renewed":
+		if err := setChirpyRed(queries, event.Data.UserID, true); err != nil {
+			return err
+		}
+		return queries.RenewSubscription(context.Background(), database.RenewSubscriptionParams{UserID: event.Data.UserID, ExpiresAt: expiresAt})
+	case "user.downgraded":
+		if err := setChirpyRed(queries, event.Data.UserID, false); err != nil {
+			return err
+		}
+		return queries.EndSubscription(context.Background(), database.EndSubscriptionParams{UserID: event.Data.UserID, Status: subscriptionCanceled})
+	case "subscription.expired":
+		if err := setChirpyRed(queries, event.Data.UserID, false); err != nil {
+			return err
+		}
+		return queries.EndSubscription(context.Background(), database.EndSubscriptionParams{UserID: event.Data.UserID, Status: subscriptionExpired})
+	}
+	return nil
+}
+
+func setChirpyRed(queries *database.Queries, userID uuid.UUID, isChirpyRed bool) error {
+	updated, err := queries.SetUserChirpyRed(context.Background(), database.SetUserChirpyRedParams{IsChirpyRed: isChirpyRed, ID: userID})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return errWebhookUserNotFound
+	}
+	return nil
+}
+
+func respondToWebhookProcessing(out http.ResponseWriter, err error) {
+	if errors.Is(err, errWebhookUserNotFound) {
+		RespondWithError(out, 404, err.Error())
+		return
+	}
+	if err != nil {
+		RespondWithError(out, 500, err.Error())
+		return
+	}
+	RespondNoContent(out, 204)
+}
+
+func (cfg *ApiConfig) HandleGetWebhookEvents(out http.ResponseWriter, req *http.Request) {
+	page, err := parsePageParams(req)
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	events, err := cfg.DB_Config.Queries.GetWebhookEvents(context.Background(), database.GetWebhookEventsParams{CursorCreatedAt: page.cursorCreatedAt(), CursorID: page.cursorID(), PageSize: page.queryLimit()})
+	if err != nil {
+		RespondWithError(out, 400, err.Error())
+		return
+	}
+	events, nextCursor := paginate(events, page, func(event database.WebhookEvent) pageCursor {
+		return pageCursor{CreatedAt: event.ReceivedAt, ID: event.ID}
+	})
+
+	mappedEvents := make([]WebhookEvent, len(events))
+	for ix, event := range events {
+		mappedEvents[ix] = FromDatabaseWebhookEvent(event)
+	}
+	eventsBytes, _ := json.Marshal(WebhookEventPage{Events: mappedEvents, NextCursor: nextCursor})
+	RespondWithJSON(out, 200, eventsBytes)
+}
+
+// HandleReplayWebhookEvent runs a stored event that failed before, e.g. an
+// upgrade for a user that didn't exist yet. Processed events are never run
+// twice.
+func (cfg *ApiConfig) HandleReplayWebhookEvent(out http.ResponseWriter, req *http.Request) {
+	alreadyProcessed, err := cfg.processWebhookEvent(req.PathValue("eventID"))
+	if errors.Is(err, sql.ErrNoRows) {
+		RespondWithError(out, 404, "Webhook event does not exist")
+		return
+	}
+	if alreadyProcessed {
+		RespondWithError(out, 409, "Webhook event was already processed")
+		return
+	}
+	respondToWebhookProcessing(out, err)
+}
@@ -0,0 +1,24 @@
+package api
+
+import "testing"
+
+func TestParsePolkaEvent(t *testing.T) {
+	event, err := parsePolkaEvent([]byte(`{"id":"evt_1","event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`))
+	if err != nil {
+		t.Fatalf("Valid payload should parse, but got error: %v", err)
+	}
+	if event.ID != "evt_1" || event.Data.UserID.String() != "3311741c-680c-4546-99f3-fc9efac2036c" {
+		t.Errorf("Payload should be parsed into the event, got %+v", event)
+	}
+
+	event, err = parsePolkaEvent([]byte(`{"id":"evt_2","event":"subscription.renewed","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c","expires_at":"2030-01-31T00:00:00Z"}}`))
+	if err != nil || event.Data.ExpiresAt == nil || event.Data.ExpiresAt.Year() != 2030 {
+		t.Errorf("Renewal should carry the end of the period, got %+v and error: %v", event, err)
+	}
+
+	for _, payload := range []string{``, `not json`, `{"event":"user.upgraded"}`, `{"id":"evt_1"}`, `{"id":"evt_1","event":"user.upgraded","data":{"user_id":"nope"}}`} {
+		if _, err := parsePolkaEvent([]byte(payload)); err == nil {
+			t.Errorf("Payload %q should be rejected", payload)
+		}
+	}
+}
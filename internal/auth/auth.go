@@ -2,15 +2,14 @@ package auth
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
-	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/alexedwards/argon2id"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
@@ -23,36 +22,12 @@ func CheckPasswordHash(password string, hash string) (bool, error) {
 	return valid, err
 }
 
-func CreateJWTToken(userID uuid.UUID, tokenSecret string, expires time.Duration) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Issuer: "chirpy", IssuedAt: jwt.NewNumericDate(time.Now().UTC()), ExpiresAt: jwt.NewNumericDate(time.Now().Add(expires).UTC()), Subject: userID.String()})
-	return token.SignedString([]byte(tokenSecret))
+func CreateJWTToken(userID uuid.UUID, role string, tokenSecret string, expires time.Duration) (string, error) {
+	return NewHMACKeySet(tokenSecret).CreateJWTToken(userID, role, expires)
 }
 
 func ValidateJWT(tokenString string, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("Unexpected signing method: %v", t.Header["alg"])
-		}
-		return []byte(tokenSecret), nil
-	})
-	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Error while parsing claims: %v", err)
-	}
-	if issuer, err := token.Claims.GetIssuer(); issuer != "chirpy" {
-		if err != nil {
-			return uuid.UUID{}, err
-		}
-		return uuid.UUID{}, fmt.Errorf("Unexpected issuer: %v", issuer)
-	}
-	subject, err := token.Claims.GetSubject()
-	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Error while getting claims: %v", err)
-	}
-	userId, err := uuid.Parse(subject)
-	if err != nil {
-		return uuid.UUID{}, fmt.Errorf("Error while parsing UUID: %v", err)
-	}
-	return userId, nil
+	return NewHMACKeySet(tokenSecret).ValidateJWT(tokenString)
 }
 
 func GetBearerToken(headers http.Header) (string, error) {
@@ -84,3 +59,18 @@ func MakeRefreshToken() (string, error) {
 
 	return hex.EncodeToString(refreshToken), nil
 }
+
+// MakeOneTimeToken returns a random opaque token for single-use links that
+// are sent by email, like password resets. Store it with HashToken.
+func MakeOneTimeToken() (string, error) {
+	return MakeRefreshToken()
+}
+
+// HashToken returns the hex encoded SHA-256 digest under which an opaque
+// token is stored. Tokens carry 256 bits of randomness, so an unsalted fast
+// hash is enough, and looking the digest up by equality leaks nothing useful
+// about the token through timing.
+func HashToken(token string) string {
+	digest := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(digest[:])
+}
@@ -29,7 +29,7 @@ func TestJWTTokenCycle(t *testing.T) {
 	tokenSecret := "secret"
 	expires := time.Second * 5
 
-	token, err := CreateJWTToken(userId, tokenSecret, expires)
+	token, err := CreateJWTToken(userId, RoleUser, tokenSecret, expires)
 	if err != nil {
 		t.Errorf("Function should create JWT token, but produces error: %v", err)
 	}
@@ -48,7 +48,7 @@ func TestJWTTokenCycleExpired(t *testing.T) {
 	tokenSecret := "secret"
 	expires := time.Millisecond
 
-	token, _ := CreateJWTToken(userId, tokenSecret, expires)
+	token, _ := CreateJWTToken(userId, RoleUser, tokenSecret, expires)
 	time.Sleep(time.Microsecond * 2)
 	validatedUUID, err := ValidateJWT(token, tokenSecret)
 	if err == nil {
@@ -56,3 +56,18 @@ func TestJWTTokenCycleExpired(t *testing.T) {
 	}
 
 }
+
+func TestHashToken(t *testing.T) {
+	token, _ := MakeRefreshToken()
+
+	hash := HashToken(token)
+	if hash == token || len(hash) != 64 {
+		t.Errorf("HashToken should return a hex SHA-256 digest, got: %v", hash)
+	}
+	if HashToken(token) != hash {
+		t.Errorf("HashToken should be deterministic")
+	}
+	if other, _ := MakeRefreshToken(); HashToken(other) == hash {
+		t.Errorf("Different tokens should not share a digest")
+	}
+}
@@ -0,0 +1,330 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// HMACKeyID is the kid of the shared JWT_SECRET key. Tokens issued before
+// key IDs were introduced carry no kid and are checked against this key.
+const HMACKeyID = "hs256"
+
+// mfaChallengeAudience marks tokens that only prove the password step of a
+// two-step login, so they can never pass as access tokens.
+const mfaChallengeAudience = "chirpy-mfa"
+
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private any
+	public  any
+	retired bool
+}
+
+// KeySet holds every key Chirpy knows about. Exactly one key signs new
+// tokens; every other key that isn't retired can still verify them, so keys
+// can be rotated without logging everybody out.
+type KeySet struct {
+	activeID string
+	keys     map[string]*signingKey
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Role string `json:"role,omitempty"`
+}
+
+type AccessClaims struct {
+	UserID uuid.UUID
+	Role   string
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeySet() *KeySet {
+	return &KeySet{keys: map[string]*signingKey{}}
+}
+
+// NewHMACKeySet returns a key set that signs and verifies with a single
+// shared secret, which is how Chirpy worked before asymmetric keys.
+func NewHMACKeySet(tokenSecret string) *KeySet {
+	keySet := NewKeySet()
+	keySet.AddHMACKey(HMACKeyID, []byte(tokenSecret))
+	keySet.SetActive(HMACKeyID)
+	return keySet
+}
+
+func (ks *KeySet) AddHMACKey(kid string, secret []byte) error {
+	if len(secret) == 0 {
+		return errors.New("HMAC secret can't be empty")
+	}
+	return ks.add(&signingKey{id: kid, method: jwt.SigningMethodHS256, private: secret, public: secret})
+}
+
+// AddPrivateKey registers an Ed25519 or RSA key that can sign tokens.
+func (ks *KeySet) AddPrivateKey(kid string, key crypto.Signer) error {
+	switch typedKey := key.(type) {
+	case ed25519.PrivateKey:
+		return ks.add(&signingKey{id: kid, method: jwt.SigningMethodEdDSA, private: typedKey, public: typedKey.Public()})
+	case *rsa.PrivateKey:
+		if typedKey.N.BitLen() < 2048 {
+			return fmt.Errorf("RSA key %v is shorter than 2048 bits", kid)
+		}
+		return ks.add(&signingKey{id: kid, method: jwt.SigningMethodRS256, private: typedKey, public: &typedKey.PublicKey})
+	default:
+		return fmt.Errorf("Unsupported private key type for %v: %T", kid, key)
+	}
+}
+
+// AddPublicKey registers a verification-only key, e.g. one whose private
+// half was already removed from this instance during a rotation.
+func (ks *KeySet) AddPublicKey(kid string, key crypto.PublicKey) error {
+	switch typedKey := key.(type) {
+	case ed25519.PublicKey:
+		return ks.add(&signingKey{id: kid, method: jwt.SigningMethodEdDSA, public: typedKey})
+	case *rsa.PublicKey:
+		return ks.add(&signingKey{id: kid, method: jwt.SigningMethodRS256, public: typedKey})
+	default:
+		return fmt.Errorf("Unsupported public key type for %v: %T", kid, key)
+	}
+}
+
+func (ks *KeySet) add(key *signingKey) error {
+	if key.id == "" {
+		return errors.New("Key ID can't be empty")
+	}
+	if _, exists := ks.keys[key.id]; exists {
+		return fmt.Errorf("Duplicate key ID: %v", key.id)
+	}
+	ks.keys[key.id] = key
+	return nil
+}
+
+func (ks *KeySet) SetActive(kid string) error {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("Unknown key ID: %v", kid)
+	}
+	if key.private == nil || key.retired {
+		return fmt.Errorf("Key %v can't be used for signing", kid)
+	}
+	ks.activeID = kid
+	return nil
+}
+
+// Retire stops a key from verifying tokens. Tokens it signed are rejected
+// from then on, and it is no longer published in the JWKS.
+func (ks *KeySet) Retire(kid string) error {
+	key, ok := ks.keys[kid]
+	if !ok {
+		return fmt.Errorf("Unknown key ID: %v", kid)
+	}
+	if kid == ks.activeID {
+		return fmt.Errorf("Key %v is used for signing and can't be retired", kid)
+	}
+	key.retired = true
+	return nil
+}
+
+// CreateJWTToken issues an access token. The role claim lets clients adapt
+// their UI; the server itself checks the role stored for the user.
+func (ks *KeySet) CreateJWTToken(userID uuid.UUID, role string, expires time.Duration) (string, error) {
+	return ks.createToken(userID, role, "", expires)
+}
+
+func (ks *KeySet) ValidateJWT(tokenString string) (uuid.UUID, error) {
+	claims, err := ks.ValidateJWTClaims(tokenString)
+	return claims.UserID, err
+}
+
+// ValidateJWTClaims validates an access token like ValidateJWT and also
+// returns the role it was issued with.
+func (ks *KeySet) ValidateJWTClaims(tokenString string) (AccessClaims, error) {
+	return ks.validateToken(tokenString, "")
+}
+
+func (ks *KeySet) CreateMFAChallengeToken(userID uuid.UUID, expires time.Duration) (string, error) {
+	return ks.createToken(userID, "", mfaChallengeAudience, expires)
+}
+
+func (ks *KeySet) ValidateMFAChallengeToken(tokenString string) (uuid.UUID, error) {
+	claims, err := ks.validateToken(tokenString, mfaChallengeAudience)
+	return claims.UserID, err
+}
+
+func (ks *KeySet) createToken(userID uuid.UUID, role string, audience string, expires time.Duration) (string, error) {
+	key, ok := ks.keys[ks.activeID]
+	if !ok {
+		return "", errors.New("No active signing key")
+	}
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{Issuer: "chirpy", IssuedAt: jwt.NewNumericDate(time.Now().UTC()), ExpiresAt: jwt.NewNumericDate(time.Now().Add(expires).UTC()), Subject: userID.String()},
+		Role:             role,
+	}
+	if audience != "" {
+		claims.Audience = jwt.ClaimStrings{audience}
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.id
+	return token.SignedString(key.private)
+}
+
+func (ks *KeySet) validateToken(tokenString string, audience string) (AccessClaims, error) {
+	claims := tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, ks.keyFunc)
+	if err != nil {
+		return AccessClaims{}, fmt.Errorf("Error while parsing claims: %v", err)
+	}
+	if issuer, err := token.Claims.GetIssuer(); issuer != "chirpy" {
+		if err != nil {
+			return AccessClaims{}, err
+		}
+		return AccessClaims{}, fmt.Errorf("Unexpected issuer: %v", issuer)
+	}
+	if audience == "" && len(claims.Audience) > 0 || audience != "" && (len(claims.Audience) != 1 || claims.Audience[0] != audience) {
+		return AccessClaims{}, fmt.Errorf("Unexpected audience: %v", claims.Audience)
+	}
+	subject, err := token.Claims.GetSubject()
+	if err != nil {
+		return AccessClaims{}, fmt.Errorf("Error while getting claims: %v", err)
+	}
+	userId, err := uuid.Parse(subject)
+	if err != nil {
+		return AccessClaims{}, fmt.Errorf("Error while parsing UUID: %v", err)
+	}
+	return AccessClaims{UserID: userId, Role: claims.Role}, nil
+}
+
+func (ks *KeySet) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		kid = HMACKeyID
+	}
+	key, ok := ks.keys[kid]
+	if !ok || key.retired {
+		return nil, fmt.Errorf("Unknown or retired key: %v", kid)
+	}
+	// The algorithm is pinned by the key, never taken from the token, so an
+	// RSA public key can't be abused as an HMAC secret.
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("Unexpected signing method: %v", t.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// JWKS lists the public halves of all asymmetric keys that can still verify
+// tokens. Shared HMAC secrets are never published.
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, key := range ks.keys {
+		if key.retired {
+			continue
+		}
+		switch publicKey := key.public.(type) {
+		case ed25519.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{Kty: "OKP", Kid: key.id, Use: "sig", Alg: key.method.Alg(), Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(publicKey)})
+		case *rsa.PublicKey:
+			jwks.Keys = append(jwks.Keys, JWK{Kty: "RSA", Kid: key.id, Use: "sig", Alg: key.method.Alg(), N: base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()), E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())})
+		}
+	}
+	sort.Slice(jwks.Keys, func(i, j int) bool { return jwks.Keys[i].Kid < jwks.Keys[j].Kid })
+	return jwks
+}
+
+// LoadKeySet builds the key set from configuration. Every <kid>.pem file in
+// keysDir holds a PKCS#8 private key or a PKIX public key. The JWT secret, if
+// set, is added as an HMAC key so tokens signed with it stay valid.
+func LoadKeySet(tokenSecret string, keysDir string, activeKid string, retiredKids []string) (*KeySet, error) {
+	keySet := NewKeySet()
+	if tokenSecret != "" {
+		keySet.AddHMACKey(HMACKeyID, []byte(tokenSecret))
+	}
+	if keysDir != "" {
+		paths, err := filepath.Glob(filepath.Join(keysDir, "*.pem"))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			if err := keySet.addPEMFile(path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if activeKid == "" {
+		activeKid = HMACKeyID
+	}
+	if err := keySet.SetActive(activeKid); err != nil {
+		return nil, err
+	}
+	for _, kid := range retiredKids {
+		if kid = strings.TrimSpace(kid); kid == "" {
+			continue
+		}
+		if err := keySet.Retire(kid); err != nil {
+			return nil, err
+		}
+	}
+	return keySet, nil
+}
+
+func (ks *KeySet) addPEMFile(path string) error {
+	kid := strings.TrimSuffix(filepath.Base(path), ".pem")
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return fmt.Errorf("No PEM data in %v", path)
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("Error while parsing %v: %v", path, err)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return fmt.Errorf("Unsupported private key in %v", path)
+		}
+		return ks.AddPrivateKey(kid, signer)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("Error while parsing %v: %v", path, err)
+		}
+		return ks.AddPublicKey(kid, key)
+	default:
+		return fmt.Errorf("Unsupported PEM block %v in %v", block.Type, path)
+	}
+}
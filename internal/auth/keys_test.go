@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func TestEd25519TokenCycle(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	keySet := NewKeySet()
+	if err := keySet.AddPrivateKey("ed-1", privateKey); err != nil {
+		t.Fatalf("Ed25519 key should be accepted, but got error: %v", err)
+	}
+	keySet.SetActive("ed-1")
+	userId := uuid.New()
+
+	token, err := keySet.CreateJWTToken(userId, RoleUser, time.Minute)
+	if err != nil {
+		t.Fatalf("Key set should create JWT token, but produces error: %v", err)
+	}
+	parsed, _, _ := jwt.NewParser().ParseUnverified(token, &jwt.RegisteredClaims{})
+	if parsed.Header["kid"] != "ed-1" || parsed.Header["alg"] != "EdDSA" {
+		t.Errorf("Token should be signed with EdDSA and kid ed-1, got header: %v", parsed.Header)
+	}
+
+	validatedUUID, err := keySet.ValidateJWT(token)
+	if err != nil {
+		t.Errorf("Key set should validate its own token, but produces error: %v", err)
+	}
+	if validatedUUID != userId {
+		t.Errorf("%v should be equal to %v", userId, validatedUUID)
+	}
+}
+
+func TestRSATokenCycle(t *testing.T) {
+	privateKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	keySet := NewKeySet()
+	keySet.AddPrivateKey("rsa-1", privateKey)
+	keySet.SetActive("rsa-1")
+	userId := uuid.New()
+
+	token, _ := keySet.CreateJWTToken(userId, RoleUser, time.Minute)
+	validatedUUID, err := keySet.ValidateJWT(token)
+	if err != nil || validatedUUID != userId {
+		t.Errorf("RS256 token should validate to %v, got %v and error: %v", userId, validatedUUID, err)
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	_, oldKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	keySet := NewKeySet()
+	keySet.AddPrivateKey("old", oldKey)
+	keySet.AddPrivateKey("new", newKey)
+	keySet.SetActive("old")
+	oldToken, _ := keySet.CreateJWTToken(uuid.New(), RoleUser, time.Minute)
+
+	keySet.SetActive("new")
+	if _, err := keySet.ValidateJWT(oldToken); err != nil {
+		t.Errorf("Token signed with a rotated out key should still validate, but got error: %v", err)
+	}
+
+	keySet.Retire("old")
+	if _, err := keySet.ValidateJWT(oldToken); err == nil {
+		t.Errorf("Token signed with a retired key should be rejected")
+	}
+	if jwks := keySet.JWKS(); len(jwks.Keys) != 1 || jwks.Keys[0].Kid != "new" {
+		t.Errorf("JWKS should only publish the new key, got: %+v", jwks.Keys)
+	}
+	if err := keySet.Retire("new"); err == nil {
+		t.Errorf("Active key should not be retirable")
+	}
+}
+
+func TestLegacyTokenWithoutKid(t *testing.T) {
+	userId := uuid.New()
+	legacyToken, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Issuer: "chirpy", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)), Subject: userId.String()}).SignedString([]byte("secret"))
+
+	validatedUUID, err := NewHMACKeySet("secret").ValidateJWT(legacyToken)
+	if err != nil || validatedUUID != userId {
+		t.Errorf("Token without kid should validate against the HMAC key, got %v and error: %v", validatedUUID, err)
+	}
+}
+
+func TestAlgorithmIsPinnedByKey(t *testing.T) {
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	keySet := NewKeySet()
+	keySet.AddPrivateKey("ed-1", privateKey)
+	keySet.SetActive("ed-1")
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Issuer: "chirpy", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)), Subject: uuid.NewString()})
+	forged.Header["kid"] = "ed-1"
+	forgedToken, _ := forged.SignedString([]byte(privateKey.Public().(ed25519.PublicKey)))
+
+	if _, err := keySet.ValidateJWT(forgedToken); err == nil {
+		t.Errorf("HS256 token claiming an Ed25519 kid should be rejected")
+	}
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+	_, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	os.WriteFile(filepath.Join(dir, "current.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600)
+	otherPublic, _, _ := ed25519.GenerateKey(rand.Reader)
+	publicDER, _ := x509.MarshalPKIXPublicKey(otherPublic)
+	os.WriteFile(filepath.Join(dir, "previous.pem"), pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600)
+
+	keySet, err := LoadKeySet("secret", dir, "current", []string{""})
+	if err != nil {
+		t.Fatalf("Key set should load, but got error: %v", err)
+	}
+	if jwks := keySet.JWKS(); len(jwks.Keys) != 2 {
+		t.Errorf("JWKS should publish both asymmetric keys and not the secret, got: %+v", jwks.Keys)
+	}
+	if _, err := LoadKeySet("secret", dir, "previous", nil); err == nil {
+		t.Errorf("Public-only key should not be usable as the active key")
+	}
+}
+
+func TestMFAChallengeTokenIsNotAnAccessToken(t *testing.T) {
+	keySet := NewHMACKeySet("secret")
+	userId := uuid.New()
+
+	challengeToken, _ := keySet.CreateMFAChallengeToken(userId, time.Minute)
+	if _, err := keySet.ValidateJWT(challengeToken); err == nil {
+		t.Errorf("MFA challenge token should not be accepted as an access token")
+	}
+	if validatedUUID, err := keySet.ValidateMFAChallengeToken(challengeToken); err != nil || validatedUUID != userId {
+		t.Errorf("MFA challenge token should validate to %v, got %v and error: %v", userId, validatedUUID, err)
+	}
+
+	accessToken, _ := keySet.CreateJWTToken(userId, RoleUser, time.Minute)
+	if _, err := keySet.ValidateMFAChallengeToken(accessToken); err == nil {
+		t.Errorf("Access token should not be accepted as an MFA challenge token")
+	}
+}
@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestHasRole(t *testing.T) {
+	cases := []struct {
+		role     string
+		required string
+		expected bool
+	}{
+		{RoleAdmin, RoleAdmin, true},
+		{RoleAdmin, RoleModerator, true},
+		{RoleModerator, RoleModerator, true},
+		{RoleModerator, RoleAdmin, false},
+		{RoleUser, RoleModerator, false},
+		{"", RoleUser, false},
+		{"superuser", RoleUser, false},
+	}
+	for _, c := range cases {
+		if HasRole(c.role, c.required) != c.expected {
+			t.Errorf("HasRole(%q, %q) should be %v", c.role, c.required, c.expected)
+		}
+	}
+}
+
+func TestRoleClaim(t *testing.T) {
+	keySet := NewHMACKeySet("secret")
+	userId := uuid.New()
+
+	token, _ := keySet.CreateJWTToken(userId, RoleModerator, time.Minute)
+	claims, err := keySet.ValidateJWTClaims(token)
+	if err != nil {
+		t.Fatalf("Token should validate, but got error: %v", err)
+	}
+	if claims.UserID != userId || claims.Role != RoleModerator {
+		t.Errorf("Claims should carry %v with role %v, got %+v", userId, RoleModerator, claims)
+	}
+}
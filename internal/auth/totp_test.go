@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors for SHA-1, truncated to 6 digits.
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unixTime, expected := range vectors {
+		code, err := TOTPCode(secret, time.Unix(unixTime, 0))
+		if err != nil {
+			t.Fatalf("TOTPCode should not fail, but produces error: %v", err)
+		}
+		if code != expected {
+			t.Errorf("Code at %d should be %v, got %v", unixTime, expected, code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, _ := GenerateTOTPSecret()
+	now := time.Now()
+	code, _ := TOTPCode(secret, now)
+
+	step, valid := ValidateTOTP(secret, code, now, 0)
+	if !valid {
+		t.Fatalf("Current code should be valid")
+	}
+	if _, valid := ValidateTOTP(secret, code, now, step); valid {
+		t.Errorf("Code for an already used step should be rejected")
+	}
+	lateCode, _ := TOTPCode(secret, now.Add(-30*time.Second))
+	if _, valid := ValidateTOTP(secret, lateCode, now, 0); !valid {
+		t.Errorf("Code from the previous step should be accepted for clock drift")
+	}
+	oldCode, _ := TOTPCode(secret, now.Add(-5*time.Minute))
+	if _, valid := ValidateTOTP(secret, oldCode, now, 0); valid && oldCode != code {
+		t.Errorf("Code from five minutes ago should be rejected")
+	}
+}
+
+func TestTOTPAuthURI(t *testing.T) {
+	uri := TOTPAuthURI("JBSWY3DPEHPK3PXP", "user@example.com", "Chirpy")
+	if !strings.HasPrefix(uri, "otpauth://totp/Chirpy:user@example.com?") || !strings.Contains(uri, "secret=JBSWY3DPEHPK3PXP") {
+		t.Errorf("Unexpected otpauth URI: %v", uri)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil || len(codes) != 10 {
+		t.Fatalf("Should generate 10 recovery codes, got %v and error: %v", codes, err)
+	}
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' || seen[code] {
+			t.Errorf("Recovery codes should be unique and look like xxxxx-xxxxx, got: %v", code)
+		}
+		seen[code] = true
+		if NormalizeRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(code, "-", ""))+" ") != code {
+			t.Errorf("Recovery code %v should survive normalization", code)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignWebhook returns the signature header for body sent at the given time:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". The timestamp is
+// signed too, so a captured request can't be replayed later with a new one.
+func SignWebhook(body []byte, secret string, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(webhookMAC(body, secret, timestamp))
+}
+
+// VerifyWebhookSignature checks a header made by SignWebhook. The header may
+// carry several v1 signatures while the sender rotates secrets; one valid
+// signature is enough.
+func VerifyWebhookSignature(header string, body []byte, secret string, now time.Time, tolerance time.Duration) error {
+	if secret == "" {
+		return errors.New("No webhook secret configured")
+	}
+	timestamp := ""
+	signatures := [][]byte{}
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			if signature, err := hex.DecodeString(value); err == nil {
+				signatures = append(signatures, signature)
+			}
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return errors.New("Malformed signature header")
+	}
+
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Invalid signature timestamp: %v", timestamp)
+	}
+	if age := now.Sub(time.Unix(sentAt, 0)); age > tolerance || age < -tolerance {
+		return errors.New("Signature timestamp is outside the tolerance")
+	}
+
+	expected := webhookMAC(body, secret, timestamp)
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			return nil
+		}
+	}
+	return errors.New("Signature does not match")
+}
+
+func webhookMAC(body []byte, secret string, timestamp string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return mac.Sum(nil)
+}
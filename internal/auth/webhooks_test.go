@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	now := time.Now()
+	header := SignWebhook(body, "secret", now)
+
+	if err := VerifyWebhookSignature(header, body, "secret", now.Add(time.Minute), 5*time.Minute); err != nil {
+		t.Errorf("Signature should be valid, but got error: %v", err)
+	}
+	if err := VerifyWebhookSignature(header, []byte(`{"id":"evt_1","event":"user.downgraded"}`), "secret", now, 5*time.Minute); err == nil {
+		t.Errorf("Signature of a modified body should be rejected")
+	}
+	if err := VerifyWebhookSignature(header, body, "other-secret", now, 5*time.Minute); err == nil {
+		t.Errorf("Signature made with another secret should be rejected")
+	}
+	if err := VerifyWebhookSignature(header, body, "secret", now.Add(10*time.Minute), 5*time.Minute); err == nil {
+		t.Errorf("Signature older than the tolerance should be rejected")
+	}
+	if err := VerifyWebhookSignature("", body, "secret", now, 5*time.Minute); err == nil {
+		t.Errorf("Missing signature should be rejected")
+	}
+}
+
+func TestWebhookSignatureDuringRotation(t *testing.T) {
+	body := []byte(`{}`)
+	now := time.Now()
+	_, newSignature, _ := strings.Cut(SignWebhook(body, "new-secret", now), ",")
+	header := SignWebhook(body, "old-secret", now) + "," + newSignature
+
+	if err := VerifyWebhookSignature(header, body, "new-secret", now, time.Minute); err != nil {
+		t.Errorf("Any matching v1 signature should be accepted, but got error: %v", err)
+	}
+}
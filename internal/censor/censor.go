@@ -0,0 +1,150 @@
+package censor
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// Strategy decides what a censored word is replaced with.
+type Strategy string
+
+const (
+	// StrategyMask replaces the word with "****", whatever its length.
+	StrategyMask Strategy = "mask"
+	// StrategyStars replaces every character of the word with "*".
+	StrategyStars Strategy = "stars"
+	// StrategyFirstLetter keeps the first character and stars the rest.
+	StrategyFirstLetter Strategy = "first_letter"
+	// StrategyReplace replaces the word with Word.Replacement.
+	StrategyReplace Strategy = "replace"
+)
+
+const mask = "****"
+
+// Word is an entry of the word list. Pattern is matched against whole words,
+// ignoring case and common leet speak like "k3rfuffl3", and may contain "*"
+// standing for any number of characters, e.g. "fornax*".
+type Word struct {
+	Pattern     string   `json:"pattern"`
+	Strategy    Strategy `json:"strategy"`
+	Replacement string   `json:"replacement,omitempty"`
+}
+
+// Validate reports whether w can be used by a Censor. An empty strategy is
+// allowed and means StrategyMask.
+func (w Word) Validate() error {
+	if strings.Trim(w.Pattern, "*") == "" {
+		return errors.New("Pattern must contain at least one character besides *")
+	}
+	for _, r := range w.Pattern {
+		if r != '*' && !isWordRune(r) {
+			return errors.New("Pattern can only contain letters, digits, leet speak and *")
+		}
+	}
+	switch w.Strategy {
+	case "", StrategyMask, StrategyStars, StrategyFirstLetter:
+		if w.Replacement != "" {
+			return errors.New("Replacement is only used by the replace strategy")
+		}
+	case StrategyReplace:
+		if strings.ContainsFunc(w.Replacement, unicode.IsControl) {
+			return errors.New("Replacement can't contain control characters")
+		}
+	default:
+		return errors.New("Strategy must be mask, stars, first_letter or replace")
+	}
+	return nil
+}
+
+type rule struct {
+	word    Word
+	pattern []rune
+}
+
+// Censor replaces words from its word list in text. It is safe for
+// concurrent use, and its list can be swapped while it is in use.
+type Censor struct {
+	mu     sync.RWMutex
+	static []rule
+	words  []rule
+}
+
+// New returns a Censor with a fixed word list, usually read from a file.
+// More words can be added at runtime with SetWords.
+func New(static []Word) (*Censor, error) {
+	rules, err := compile(static)
+	if err != nil {
+		return nil, err
+	}
+	return &Censor{static: rules}, nil
+}
+
+// SetWords replaces the words added at runtime. The list passed to New is
+// kept.
+func (c *Censor) SetWords(words []Word) error {
+	rules, err := compile(words)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.words = rules
+	return nil
+}
+
+func compile(words []Word) ([]rule, error) {
+	rules := make([]rule, len(words))
+	for ix, word := range words {
+		if err := word.Validate(); err != nil {
+			return nil, errors.New(word.Pattern + ": " + err.Error())
+		}
+		rules[ix] = rule{word: word, pattern: normalize(word.Pattern)}
+	}
+	return rules, nil
+}
+
+// Clean returns text with every listed word replaced. Everything between
+// words, like punctuation and line breaks, is kept as it is.
+func (c *Censor) Clean(text string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	cleaned := strings.Builder{}
+	cleaned.Grow(len(text))
+	for token := range tokens(text) {
+		if !token.word {
+			cleaned.WriteString(token.text)
+			continue
+		}
+		cleaned.WriteString(c.replace(token.text))
+	}
+	return cleaned.String()
+}
+
+func (c *Censor) replace(word string) string {
+	normalized := normalize(word)
+	for _, rules := range [][]rule{c.static, c.words} {
+		for _, rule := range rules {
+			if matchWildcard(rule.pattern, normalized) {
+				return rule.word.apply(word)
+			}
+		}
+	}
+	return word
+}
+
+func (w Word) apply(word string) string {
+	switch w.Strategy {
+	case StrategyStars:
+		return strings.Repeat("*", len([]rune(word)))
+	case StrategyFirstLetter:
+		runes := []rune(word)
+		return string(runes[0]) + strings.Repeat("*", len(runes)-1)
+	case StrategyReplace:
+		return w.Replacement
+	default:
+		return mask
+	}
+}
@@ -0,0 +1,129 @@
+package censor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClean(t *testing.T) {
+	c, err := New(DefaultWords)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"I had something interesting for breakfast":   "I had something interesting for breakfast",
+		"This is a kerfuffle opinion I need to share": "This is a **** opinion I need to share",
+		"What a Kerfuffle!":                           "What a ****!",
+		"sharbert\nfornax,kerfuffle":                  "****\n****,****",
+		"k3rfuffl3 and sh@rbert":                      "**** and ****",
+		"kerfuffles are fine":                         "kerfuffles are fine",
+		"Zażółć gęślą jaźń, fornax.":                  "Zażółć gęślą jaźń, ****.",
+	}
+	for text, expected := range cases {
+		if cleaned := c.Clean(text); cleaned != expected {
+			t.Errorf("Clean(%q) should be %q, but got %q", text, expected, cleaned)
+		}
+	}
+}
+
+func TestCleanWildcardsAndStrategies(t *testing.T) {
+	c, _ := New(nil)
+	err := c.SetWords([]Word{
+		{Pattern: "fornax*", Strategy: StrategyStars},
+		{Pattern: "*bert", Strategy: StrategyFirstLetter},
+		{Pattern: "kerfuffle", Strategy: StrategyReplace, Replacement: "fuss"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"fornaxes":   "********",
+		"Sharbert":   "S*******",
+		"Kerfuffle?": "fuss?",
+		"bertha":     "bertha",
+	}
+	for text, expected := range cases {
+		if cleaned := c.Clean(text); cleaned != expected {
+			t.Errorf("Clean(%q) should be %q, but got %q", text, expected, cleaned)
+		}
+	}
+
+	if err := c.SetWords(nil); err != nil {
+		t.Fatal(err)
+	}
+	if cleaned := c.Clean("fornaxes"); cleaned != "fornaxes" {
+		t.Errorf("Words removed with SetWords should no longer be censored, but got %q", cleaned)
+	}
+}
+
+func TestMatchWildcard(t *testing.T) {
+	cases := []struct {
+		pattern, word string
+		match         bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abcd", false},
+		{"a*c", "abbbc", true},
+		{"a*c", "ac", true},
+		{"*b*", "abc", true},
+		{"a*b*c", "axbxbxc", true},
+		{"a*b", "abc", false},
+	}
+	for _, tc := range cases {
+		if matched := matchWildcard([]rune(tc.pattern), []rune(tc.word)); matched != tc.match {
+			t.Errorf("matchWildcard(%q, %q) should be %v, but got %v", tc.pattern, tc.word, tc.match, matched)
+		}
+	}
+}
+
+func TestWordValidate(t *testing.T) {
+	invalid := []Word{
+		{Pattern: ""},
+		{Pattern: "**"},
+		{Pattern: "two words"},
+		{Pattern: "word", Strategy: "explode"},
+		{Pattern: "word", Strategy: StrategyMask, Replacement: "x"},
+	}
+	for _, word := range invalid {
+		if err := word.Validate(); err == nil {
+			t.Errorf("%+v should be invalid", word)
+		}
+	}
+	if err := (Word{Pattern: "fornax*", Strategy: StrategyReplace, Replacement: "[removed]"}).Validate(); err != nil {
+		t.Errorf("Wildcard word with a replacement should be valid, but got %v", err)
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# profanity\nkerfuffle\n\nfornax*  first_letter\nsharbert replace  [not nice] \n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	words, err := LoadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Word{
+		{Pattern: "kerfuffle"},
+		{Pattern: "fornax*", Strategy: StrategyFirstLetter},
+		{Pattern: "sharbert", Strategy: StrategyReplace, Replacement: "[not nice]"},
+	}
+	if len(words) != len(expected) {
+		t.Fatalf("LoadFile should read %d words, but got %+v", len(expected), words)
+	}
+	for ix := range expected {
+		if words[ix] != expected[ix] {
+			t.Errorf("Word %d should be %+v, but got %+v", ix, expected[ix], words[ix])
+		}
+	}
+
+	if err := os.WriteFile(path, []byte("kerfuffle explode\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFile(path); err == nil {
+		t.Errorf("LoadFile should reject unknown strategies")
+	}
+}
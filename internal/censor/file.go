@@ -0,0 +1,59 @@
+package censor
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// DefaultWords is the word list used when no file is configured.
+var DefaultWords = []Word{
+	{Pattern: "kerfuffle", Strategy: StrategyMask},
+	{Pattern: "sharbert", Strategy: StrategyMask},
+	{Pattern: "fornax", Strategy: StrategyMask},
+}
+
+// LoadFile reads a word list with one word per line, written as
+//
+//	pattern [strategy [replacement]]
+//
+// The replacement is the rest of the line and may contain spaces. Empty
+// lines and lines starting with "#" are skipped.
+func LoadFile(path string) ([]Word, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	words := []Word{}
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern, rest := cutField(line)
+		strategy, replacement := cutField(rest)
+		word := Word{Pattern: pattern, Strategy: Strategy(strategy), Replacement: replacement}
+		if err := word.Validate(); err != nil {
+			return nil, fmt.Errorf("%v:%d: %v", path, lineNumber, err)
+		}
+		words = append(words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return words, nil
+}
+
+// cutField splits line at its first run of white space.
+func cutField(line string) (string, string) {
+	end := strings.IndexFunc(line, unicode.IsSpace)
+	if end < 0 {
+		return line, ""
+	}
+	return line[:end], strings.TrimSpace(line[end:])
+}
@@ -0,0 +1,96 @@
+package censor
+
+import (
+	"iter"
+	"unicode"
+	"unicode/utf8"
+)
+
+// leet maps characters commonly used to disguise letters to those letters.
+var leet = map[rune]rune{
+	'0': 'o',
+	'1': 'i',
+	'3': 'e',
+	'4': 'a',
+	'5': 's',
+	'7': 't',
+	'@': 'a',
+	'$': 's',
+}
+
+type token struct {
+	text string
+	word bool
+}
+
+// isWordRune reports whether r can be part of a word. Letters of any script
+// count, and so do digits and the symbols of leet, so "sh@rbert" stays one
+// word while the "!" in "kerfuffle!" doesn't.
+func isWordRune(r rune) bool {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) {
+		return true
+	}
+	_, isLeet := leet[r]
+	return isLeet
+}
+
+// tokens splits text into alternating runs of word and non-word characters.
+// Joining all tokens gives back text unchanged.
+func tokens(text string) iter.Seq[token] {
+	return func(yield func(token) bool) {
+		start := 0
+		inWord := false
+		for ix, r := range text {
+			if isWordRune(r) == inWord {
+				continue
+			}
+			if ix > start && !yield(token{text: text[start:ix], word: inWord}) {
+				return
+			}
+			start = ix
+			inWord = !inWord
+		}
+		if start < len(text) {
+			yield(token{text: text[start:], word: inWord})
+		}
+	}
+}
+
+func normalize(word string) []rune {
+	normalized := make([]rune, 0, utf8.RuneCountInString(word))
+	for _, r := range word {
+		if letter, isLeet := leet[r]; isLeet {
+			r = letter
+		}
+		normalized = append(normalized, unicode.ToLower(r))
+	}
+	return normalized
+}
+
+// matchWildcard reports whether word matches pattern as a whole, where "*"
+// in pattern matches any number of runes.
+func matchWildcard(pattern, word []rune) bool {
+	px, wx := 0, 0
+	// Position of the last "*" seen and of the word when it was seen, to
+	// backtrack to when the rest of the pattern stops matching.
+	star, starWord := -1, 0
+	for wx < len(word) {
+		switch {
+		case px < len(pattern) && pattern[px] == '*':
+			star, starWord = px, wx
+			px++
+		case px < len(pattern) && pattern[px] == word[wx]:
+			px++
+			wx++
+		case star >= 0:
+			starWord++
+			px, wx = star+1, starWord
+		default:
+			return false
+		}
+	}
+	for px < len(pattern) && pattern[px] == '*' {
+		px++
+	}
+	return px == len(pattern)
+}
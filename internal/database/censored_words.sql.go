@@ -0,0 +1,85 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: censored_words.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+const createCensoredWord = `-- name: CreateCensoredWord :one
+INSERT INTO censored_words(id, pattern, strategy, replacement, created_at)
+VALUES (
+	gen_random_uuid(), $1, $2, $3, NOW()
+)
+ON CONFLICT(pattern) DO NOTHING
+RETURNING id, pattern, strategy, replacement, created_at
+`
+
+type CreateCensoredWordParams struct {
+	Pattern     string
+	Strategy    string
+	Replacement string
+}
+
+func (q *Queries) CreateCensoredWord(ctx context.Context, arg CreateCensoredWordParams) (CensoredWord, error) {
+	row := q.db.QueryRowContext(ctx, createCensoredWord, arg.Pattern, arg.Strategy, arg.Replacement)
+	var i CensoredWord
+	err := row.Scan(
+		&i.ID,
+		&i.Pattern,
+		&i.Strategy,
+		&i.Replacement,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const deleteCensoredWord = `-- name: DeleteCensoredWord :execrows
+DELETE FROM censored_words WHERE id = $1
+`
+
+func (q *Queries) DeleteCensoredWord(ctx context.Context, id uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, deleteCensoredWord, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getCensoredWords = `-- name: GetCensoredWords :many
+SELECT id, pattern, strategy, replacement, created_at FROM censored_words ORDER BY created_at, id
+`
+
+func (q *Queries) GetCensoredWords(ctx context.Context) ([]CensoredWord, error) {
+	rows, err := q.db.QueryContext(ctx, getCensoredWords)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CensoredWord
+	for rows.Next() {
+		var i CensoredWord
+		if err := rows.Scan(
+			&i.ID,
+			&i.Pattern,
+			&i.Strategy,
+			&i.Replacement,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
@@ -7,25 +7,80 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const countChirpReplies = `-- name: CountChirpReplies :one
+SELECT count(*) FROM chirps WHERE parent_id = $1
+`
+
+func (q *Queries) CountChirpReplies(ctx context.Context, parentID uuid.NullUUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpReplies, parentID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countChirpsSubmittedLastDay = `-- name: CountChirpsSubmittedLastDay :one
+SELECT count(*) FROM chirps WHERE user_id = $1 AND submitted_at > NOW() - interval '24 hours'
+`
+
+func (q *Queries) CountChirpsSubmittedLastDay(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countChirpsSubmittedLastDay, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countVisibleChirpReplies = `-- name: CountVisibleChirpReplies :one
+SELECT count(*) FROM chirps
+WHERE parent_id = $1
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND ($2::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = $2::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = $2::uuid))
+`
+
+type CountVisibleChirpRepliesParams struct {
+	ParentID uuid.NullUUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) CountVisibleChirpReplies(ctx context.Context, arg CountVisibleChirpRepliesParams) (int64, error) {
+	row := q.db.QueryRowContext(ctx, countVisibleChirpReplies, arg.ParentID, arg.ViewerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body,user_id)
+INSERT INTO chirps(id, created_at, updated_at, submitted_at, body, user_id, parent_id)
 VALUES (
-	gen_random_uuid(), NOW(),NOW(), $1,$2
+	gen_random_uuid(), COALESCE($1::timestamptz, NOW()), COALESCE($1::timestamptz, NOW()), NOW(),
+	$2, $3, $4
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	PublishAt sql.NullTime
+	Body      string
+	UserID    uuid.UUID
+	ParentID  uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp,
+		arg.PublishAt,
+		arg.Body,
+		arg.UserID,
+		arg.ParentID,
+	)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -33,6 +88,12 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.SubmittedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
@@ -47,11 +108,34 @@ func (q *Queries) DeleteChirpByID(ctx context.Context, id uuid.UUID) error {
 }
 
 const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps ORDER BY created_at asc
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps
+WHERE deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND ($1::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = $1::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = $1::uuid))
+	AND ($2::timestamp IS NULL
+	OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at asc, id asc
+LIMIT $4
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+type GetAllChirpsParams struct {
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetAllChirps(ctx context.Context, arg GetAllChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirps,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +149,136 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.SubmittedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllChirpsDesc = `-- name: GetAllChirpsDesc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps
+WHERE deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND ($1::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = $1::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = $1::uuid))
+	AND ($2::timestamp IS NULL
+	OR (created_at, id) < ($2::timestamp, $3::uuid))
+ORDER BY created_at desc, id desc
+LIMIT $4
+`
+
+type GetAllChirpsDescParams struct {
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetAllChirpsDesc(ctx context.Context, arg GetAllChirpsDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getAllChirpsDesc,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.SubmittedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+	SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.search_vector, parent.edited_at, parent.parent_id, parent.deleted_at, parent.submitted_at, parent.hidden_at, 1 AS depth FROM chirps parent
+	WHERE parent.id = (SELECT child.parent_id FROM chirps child WHERE child.id = $1)
+	UNION ALL
+	SELECT parent.id, parent.created_at, parent.updated_at, parent.body, parent.user_id, parent.search_vector, parent.edited_at, parent.parent_id, parent.deleted_at, parent.submitted_at, parent.hidden_at, ancestors.depth + 1 FROM chirps parent, ancestors
+	WHERE parent.id = ancestors.parent_id AND ancestors.depth < $2::int
+)
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_id, deleted_at, hidden_at
+FROM ancestors ORDER BY depth desc
+`
+
+type GetChirpAncestorsParams struct {
+	ChirpID  uuid.UUID
+	MaxDepth int32
+}
+
+type GetChirpAncestorsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	EditedAt  sql.NullTime
+	ParentID  uuid.NullUUID
+	DeletedAt sql.NullTime
+	HiddenAt  sql.NullTime
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.MaxDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpAncestorsRow
+	for rows.Next() {
+		var i GetChirpAncestorsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -80,7 +294,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpByID = `-- name: GetChirpByID :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE id = $1
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps WHERE id = $1
 `
 
 func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -92,16 +306,179 @@ func (q *Queries) GetChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.SearchVector,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.SubmittedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getChirpByIDForUpdate = `-- name: GetChirpByIDForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetChirpByIDForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIDForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.SubmittedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
+const getChirpReplies = `-- name: GetChirpReplies :many
+WITH RECURSIVE viewer_hidden_users AS (
+	SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = $2::uuid
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = $2::uuid
+), replies AS (
+	SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.edited_at, chirps.parent_id, chirps.deleted_at, chirps.submitted_at, chirps.hidden_at, 1 AS depth FROM chirps
+	WHERE chirps.parent_id = $3::uuid AND chirps.created_at <= NOW() AND chirps.hidden_at IS NULL
+		AND chirps.user_id NOT IN (SELECT user_id FROM viewer_hidden_users)
+	UNION ALL
+	SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.edited_at, chirps.parent_id, chirps.deleted_at, chirps.submitted_at, chirps.hidden_at, replies.depth + 1 FROM chirps, replies
+	WHERE chirps.parent_id = replies.id AND chirps.created_at <= NOW() AND chirps.hidden_at IS NULL AND replies.depth < $4::int
+		AND chirps.user_id NOT IN (SELECT user_id FROM viewer_hidden_users)
+)
+SELECT replies.id, replies.created_at, replies.updated_at, replies.body, replies.user_id, replies.edited_at, replies.parent_id, replies.deleted_at,
+	(SELECT count(*) FROM chirps WHERE chirps.parent_id = replies.id AND chirps.created_at <= NOW() AND chirps.hidden_at IS NULL
+		AND chirps.user_id NOT IN (SELECT user_id FROM viewer_hidden_users)) AS reply_count
+FROM replies
+ORDER BY replies.depth, replies.created_at, replies.id
+LIMIT $1
+`
+
+type GetChirpRepliesParams struct {
+	MaxReplies int32
+	ViewerID   uuid.NullUUID
+	ChirpID    uuid.UUID
+	MaxDepth   int32
+}
+
+type GetChirpRepliesRow struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	Body       string
+	UserID     uuid.UUID
+	EditedAt   sql.NullTime
+	ParentID   uuid.NullUUID
+	DeletedAt  sql.NullTime
+	ReplyCount int64
+}
+
+func (q *Queries) GetChirpReplies(ctx context.Context, arg GetChirpRepliesParams) ([]GetChirpRepliesRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpReplies,
+		arg.MaxReplies,
+		arg.ViewerID,
+		arg.ChirpID,
+		arg.MaxDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpRepliesRow
+	for rows.Next() {
+		var i GetChirpRepliesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.ReplyCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpThreadRoot = `-- name: GetChirpThreadRoot :one
+WITH RECURSIVE chain AS (
+	SELECT id, parent_id FROM chirps WHERE chirps.id = $1
+	UNION ALL
+	SELECT parent.id, parent.parent_id FROM chirps parent, chain
+	WHERE parent.id = chain.parent_id
+)
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps WHERE id = (SELECT chain.id FROM chain WHERE chain.parent_id IS NULL)
+`
+
+func (q *Queries) GetChirpThreadRoot(ctx context.Context, chirpID uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpThreadRoot, chirpID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.SubmittedAt,
+		&i.HiddenAt,
 	)
 	return i, err
 }
 
 const getChirpsByUserID = `-- name: GetChirpsByUserID :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps WHERE user_id = $1 ORDER BY created_at asc
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps
+WHERE user_id = $1
+	AND deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND ($2::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = $2::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = $2::uuid))
+	AND ($3::timestamp IS NULL
+	OR (created_at, id) > ($3::timestamp, $4::uuid))
+ORDER BY created_at asc, id asc
+LIMIT $5
 `
 
-func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserID, userID)
+type GetChirpsByUserIDParams struct {
+	UserID          uuid.UUID
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirpsByUserID(ctx context.Context, arg GetChirpsByUserIDParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUserID,
+		arg.UserID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +492,12 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.SearchVector,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.SubmittedAt,
+			&i.HiddenAt,
 		); err != nil {
 			return nil, err
 		}
@@ -129,6 +512,215 @@ func (q *Queries) GetChirpsByUserID(ctx context.Context, userID uuid.UUID) ([]Ch
 	return items, nil
 }
 
+const getChirpsByUserIDDesc = `-- name: GetChirpsByUserIDDesc :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps
+WHERE user_id = $1
+	AND deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND ($2::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = $2::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = $2::uuid))
+	AND ($3::timestamp IS NULL
+	OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at desc, id desc
+LIMIT $5
+`
+
+type GetChirpsByUserIDDescParams struct {
+	UserID          uuid.UUID
+	ViewerID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetChirpsByUserIDDesc(ctx context.Context, arg GetChirpsByUserIDDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByUserIDDesc,
+		arg.UserID,
+		arg.ViewerID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.SubmittedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getScheduledChirps = `-- name: GetScheduledChirps :many
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps
+WHERE user_id = $1
+	AND created_at > NOW()
+	AND ($2::timestamp IS NULL
+	OR (created_at, id) > ($2::timestamp, $3::uuid))
+ORDER BY created_at asc, id asc
+LIMIT $4
+`
+
+type GetScheduledChirpsParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetScheduledChirps(ctx context.Context, arg GetScheduledChirpsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getScheduledChirps,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.SubmittedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTimeline = `-- name: GetTimeline :many
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.edited_at, chirps.parent_id, chirps.deleted_at, chirps.submitted_at, chirps.hidden_at FROM chirps
+JOIN follows ON follows.followed_id = chirps.user_id
+WHERE follows.follower_id = $1
+	AND chirps.deleted_at IS NULL
+	AND chirps.created_at <= NOW()
+	AND chirps.hidden_at IS NULL
+	AND chirps.user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = $1
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = $1)
+	AND ($2::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < ($2::timestamp, $3::uuid))
+ORDER BY chirps.created_at desc, chirps.id desc
+LIMIT $4
+`
+
+type GetTimelineParams struct {
+	UserID          uuid.UUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetTimeline(ctx context.Context, arg GetTimelineParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getTimeline,
+		arg.UserID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.SearchVector,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.SubmittedAt,
+			&i.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getVisibleChirpByID = `-- name: GetVisibleChirpByID :one
+SELECT id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at FROM chirps WHERE id = $1 AND created_at <= NOW() AND hidden_at IS NULL
+`
+
+func (q *Queries) GetVisibleChirpByID(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirpByID, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.SubmittedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
+
 const resetChirps = `-- name: ResetChirps :exec
 DELETE FROM chirps
 `
@@ -137,3 +729,151 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const searchChirps = `-- name: SearchChirps :many
+WITH matches AS (
+	SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.edited_at, chirps.parent_id, chirps.deleted_at, chirps.submitted_at, chirps.hidden_at, ts_rank(chirps.search_vector, query)::float8 AS rank
+	FROM chirps, websearch_to_tsquery('english', $1) query
+	WHERE chirps.search_vector @@ query
+		AND chirps.deleted_at IS NULL
+		AND chirps.created_at <= NOW()
+		AND chirps.hidden_at IS NULL
+		AND ($6::uuid IS NULL OR chirps.user_id NOT IN (
+			SELECT blocked_id FROM blocks WHERE blocker_id = $6::uuid
+			UNION ALL
+			SELECT muted_id FROM mutes WHERE muter_id = $6::uuid))
+		AND ($7::uuid IS NULL OR chirps.user_id = $7::uuid)
+		AND ($8::timestamp IS NULL OR chirps.created_at >= $8::timestamp)
+		AND ($9::timestamp IS NULL OR chirps.created_at < $9::timestamp)
+)
+SELECT matches.id, matches.created_at, matches.updated_at, matches.body, matches.user_id, matches.edited_at, matches.parent_id, matches.rank,
+	ts_headline('english', matches.body, websearch_to_tsquery('english', $1), 'StartSel=' || chr(1) || ', StopSel=' || chr(2) || ', MaxFragments=2')::text AS snippet
+FROM matches
+WHERE $2::float8 IS NULL
+	OR (matches.rank, matches.created_at, matches.id) < ($2::float8, $3::timestamp, $4::uuid)
+ORDER BY matches.rank desc, matches.created_at desc, matches.id desc
+LIMIT $5
+`
+
+type SearchChirpsParams struct {
+	Query           string
+	CursorRank      sql.NullFloat64
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+	ViewerID        uuid.NullUUID
+	AuthorID        uuid.NullUUID
+	CreatedAfter    sql.NullTime
+	CreatedBefore   sql.NullTime
+}
+
+type SearchChirpsRow struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Body      string
+	UserID    uuid.UUID
+	EditedAt  sql.NullTime
+	ParentID  uuid.NullUUID
+	Rank      float64
+	Snippet   string
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.CursorRank,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+		arg.ViewerID,
+		arg.AuthorID,
+		arg.CreatedAfter,
+		arg.CreatedBefore,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.Rank,
+			&i.Snippet,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const setChirpHidden = `-- name: SetChirpHidden :execrows
+UPDATE chirps SET hidden_at = CASE WHEN $1::bool THEN COALESCE(hidden_at, NOW()) END
+WHERE id = $2
+`
+
+type SetChirpHiddenParams struct {
+	Hidden bool
+	ID     uuid.UUID
+}
+
+func (q *Queries) SetChirpHidden(ctx context.Context, arg SetChirpHiddenParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, setChirpHidden, arg.Hidden, arg.ID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :exec
+UPDATE chirps SET updated_at = NOW(), deleted_at = NOW(), body = '' WHERE id = $1
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, tombstoneChirp, id)
+	return err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps SET updated_at = NOW(), edited_at = NOW(), body = $1 WHERE id = $2
+RETURNING id, created_at, updated_at, body, user_id, search_vector, edited_at, parent_id, deleted_at, submitted_at, hidden_at
+`
+
+type UpdateChirpBodyParams struct {
+	Body string
+	ID   uuid.UUID
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.Body, arg.ID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.SearchVector,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.SubmittedAt,
+		&i.HiddenAt,
+	)
+	return i, err
+}
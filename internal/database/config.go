@@ -2,7 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
+
+	"github.com/lib/pq"
 )
 
 type DatabaseConfig struct {
@@ -19,3 +22,10 @@ func InitializeDatabase(dbUrl string) DatabaseConfig {
 	fmt.Printf("Successfully connected to database: %v\n", dbUrl)
 	return DatabaseConfig{Db_connection: db, Queries: New(db)}
 }
+
+// IsUniqueViolation reports whether err was caused by the unique constraint
+// or index named constraint.
+func IsUniqueViolation(err error, constraint string) bool {
+	pqErr := &pq.Error{}
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
@@ -0,0 +1,74 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: login_failures.sql
+
+package database
+
+import (
+	"context"
+
+	"github.com/lib/pq"
+)
+
+const clearLoginFailures = `-- name: ClearLoginFailures :exec
+DELETE FROM login_failures WHERE key = $1
+`
+
+func (q *Queries) ClearLoginFailures(ctx context.Context, key string) error {
+	_, err := q.db.ExecContext(ctx, clearLoginFailures, key)
+	return err
+}
+
+const getLoginLockoutSeconds = `-- name: GetLoginLockoutSeconds :one
+SELECT COALESCE(CEIL(EXTRACT(EPOCH FROM MAX(locked_until) - NOW())), 0)::int AS retry_after
+FROM login_failures
+WHERE key = ANY($1::text[]) AND locked_until > NOW()
+`
+
+func (q *Queries) GetLoginLockoutSeconds(ctx context.Context, keys []string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, getLoginLockoutSeconds, pq.Array(keys))
+	var retry_after int32
+	err := row.Scan(&retry_after)
+	return retry_after, err
+}
+
+const lockLogin = `-- name: LockLogin :exec
+UPDATE login_failures SET locked_until = NOW() + $1::int * INTERVAL '1 second'
+WHERE key = $2
+`
+
+type LockLoginParams struct {
+	LockSeconds int32
+	Key         string
+}
+
+func (q *Queries) LockLogin(ctx context.Context, arg LockLoginParams) error {
+	_, err := q.db.ExecContext(ctx, lockLogin, arg.LockSeconds, arg.Key)
+	return err
+}
+
+const recordLoginFailure = `-- name: RecordLoginFailure :one
+INSERT INTO login_failures(key, failure_count, last_failure_at, locked_until)
+VALUES ($1, 1, NOW(), NULL)
+ON CONFLICT(key) DO UPDATE SET
+	failure_count = CASE WHEN login_failures.last_failure_at < NOW() - INTERVAL '24 hours' THEN 1 ELSE login_failures.failure_count + 1 END,
+	last_failure_at = NOW()
+RETURNING failure_count
+`
+
+func (q *Queries) RecordLoginFailure(ctx context.Context, key string) (int32, error) {
+	row := q.db.QueryRowContext(ctx, recordLoginFailure, key)
+	var failure_count int32
+	err := row.Scan(&failure_count)
+	return failure_count, err
+}
+
+const resetLoginFailures = `-- name: ResetLoginFailures :exec
+DELETE FROM login_failures
+`
+
+func (q *Queries) ResetLoginFailures(ctx context.Context) error {
+	_, err := q.db.ExecContext(ctx, resetLoginFailures)
+	return err
+}
@@ -6,33 +6,180 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+type Block struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type CensoredWord struct {
+	ID          uuid.UUID
+	Pattern     string
+	Strategy    string
+	Replacement string
+	CreatedAt   time.Time
+}
+
 type Chirp struct {
+	ID           uuid.UUID
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	Body         string
+	UserID       uuid.UUID
+	SearchVector interface{}
+	EditedAt     sql.NullTime
+	ParentID     uuid.NullUUID
+	DeletedAt    sql.NullTime
+	SubmittedAt  time.Time
+	HiddenAt     sql.NullTime
+}
+
+type ChirpLike struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID         uuid.UUID
+	CreatedAt  time.Time
+	ReplacedAt time.Time
+	Body       string
+	ChirpID    uuid.UUID
+}
+
+type EmailVerificationToken struct {
+	TokenHash string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	Email     string
+	UserID    uuid.UUID
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FollowedID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginFailure struct {
+	Key           string
+	FailureCount  int32
+	LastFailureAt time.Time
+	LockedUntil   sql.NullTime
+}
+
+type MfaRecoveryCode struct {
 	ID        uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
-	Body      string
+	UsedAt    sql.NullTime
+	CodeHash  string
 	UserID    uuid.UUID
 }
 
-type RefreshToken struct {
-	Token     string
+type ModerationAction struct {
+	ID             uuid.UUID
+	ModeratorID    uuid.UUID
+	Action         string
+	ChirpID        uuid.NullUUID
+	UserID         uuid.NullUUID
+	ReportID       uuid.NullUUID
+	Reason         string
+	SuspendedUntil sql.NullTime
+	CreatedAt      time.Time
+}
+
+type Mute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type PasswordResetToken struct {
+	TokenHash string
 	CreatedAt time.Time
-	UpdatedAt time.Time
 	ExpiresAt time.Time
-	RevokedAt sql.NullTime
+	UsedAt    sql.NullTime
+	UserID    uuid.UUID
+}
+
+type RateLimitBucket struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+	FullAt    time.Time
+}
+
+type RefreshToken struct {
+	TokenHash     string
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+	ExpiresAt     time.Time
+	RevokedAt     sql.NullTime
+	UserID        uuid.UUID
+	FamilyID      uuid.UUID
+	RevokedReason sql.NullString
+	ReplacedBy    sql.NullString
+	LastUsedAt    time.Time
+	UserAgent     string
+	IpAddress     string
+}
+
+type Report struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+	Status     string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+	ResolvedBy uuid.NullUUID
+}
+
+type Subscription struct {
 	UserID    uuid.UUID
+	Plan      string
+	Status    string
+	StartedAt time.Time
+	ExpiresAt sql.NullTime
+	EndedAt   sql.NullTime
+	UpdatedAt time.Time
 }
 
 type User struct {
-	ID             uuid.UUID
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	Email          string
-	HashedPassword string
-	IsChirpyRed    bool
+	ID               uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	Email            string
+	HashedPassword   string
+	IsChirpyRed      bool
+	TotpSecret       sql.NullString
+	TotpEnabledAt    sql.NullTime
+	TotpLastUsedStep int64
+	EmailVerifiedAt  sql.NullTime
+	Role             string
+	SuspendedUntil   sql.NullTime
+	Handle           string
+	DisplayName      string
+	Bio              string
+	AvatarUrl        string
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID
+	EventID     string
+	Event       string
+	Payload     json.RawMessage
+	ReceivedAt  time.Time
+	ProcessedAt sql.NullTime
+	Attempts    int32
+	LastError   sql.NullString
 }
@@ -0,0 +1,113 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: moderation_actions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const createModerationAction = `-- name: CreateModerationAction :one
+INSERT INTO moderation_actions(id, moderator_id, action, chirp_id, user_id, report_id, reason, suspended_until, created_at)
+VALUES (
+	gen_random_uuid(), $1, $2, $3, $4, $5, $6, $7, NOW()
+)
+RETURNING id, moderator_id, action, chirp_id, user_id, report_id, reason, suspended_until, created_at
+`
+
+type CreateModerationActionParams struct {
+	ModeratorID    uuid.UUID
+	Action         string
+	ChirpID        uuid.NullUUID
+	UserID         uuid.NullUUID
+	ReportID       uuid.NullUUID
+	Reason         string
+	SuspendedUntil sql.NullTime
+}
+
+func (q *Queries) CreateModerationAction(ctx context.Context, arg CreateModerationActionParams) (ModerationAction, error) {
+	row := q.db.QueryRowContext(ctx, createModerationAction,
+		arg.ModeratorID,
+		arg.Action,
+		arg.ChirpID,
+		arg.UserID,
+		arg.ReportID,
+		arg.Reason,
+		arg.SuspendedUntil,
+	)
+	var i ModerationAction
+	err := row.Scan(
+		&i.ID,
+		&i.ModeratorID,
+		&i.Action,
+		&i.ChirpID,
+		&i.UserID,
+		&i.ReportID,
+		&i.Reason,
+		&i.SuspendedUntil,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getModerationActions = `-- name: GetModerationActions :many
+SELECT id, moderator_id, action, chirp_id, user_id, report_id, reason, suspended_until, created_at FROM moderation_actions
+WHERE ($1::uuid IS NULL OR user_id = $1::uuid)
+	AND ($2::uuid IS NULL OR chirp_id = $2::uuid)
+	AND ($3::timestamp IS NULL
+	OR (created_at, id) < ($3::timestamp, $4::uuid))
+ORDER BY created_at desc, id desc
+LIMIT $5
+`
+
+type GetModerationActionsParams struct {
+	UserID          uuid.NullUUID
+	ChirpID         uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetModerationActions(ctx context.Context, arg GetModerationActionsParams) ([]ModerationAction, error) {
+	rows, err := q.db.QueryContext(ctx, getModerationActions,
+		arg.UserID,
+		arg.ChirpID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ModerationAction
+	for rows.Next() {
+		var i ModerationAction
+		if err := rows.Scan(
+			&i.ID,
+			&i.ModeratorID,
+			&i.Action,
+			&i.ChirpID,
+			&i.UserID,
+			&i.ReportID,
+			&i.Reason,
+			&i.SuspendedUntil,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
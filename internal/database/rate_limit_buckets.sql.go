@@ -0,0 +1,74 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: rate_limit_buckets.sql
+
+package database
+
+import (
+	"context"
+	"time"
+)
+
+const createRateLimitBucket = `-- name: CreateRateLimitBucket :exec
+INSERT INTO rate_limit_buckets(key, tokens, updated_at, full_at)
+VALUES ($1, $2, $3, $3)
+ON CONFLICT(key) DO NOTHING
+`
+
+type CreateRateLimitBucketParams struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+func (q *Queries) CreateRateLimitBucket(ctx context.Context, arg CreateRateLimitBucketParams) error {
+	_, err := q.db.ExecContext(ctx, createRateLimitBucket, arg.Key, arg.Tokens, arg.UpdatedAt)
+	return err
+}
+
+const deleteFullRateLimitBuckets = `-- name: DeleteFullRateLimitBuckets :exec
+DELETE FROM rate_limit_buckets WHERE full_at <= $1
+`
+
+func (q *Queries) DeleteFullRateLimitBuckets(ctx context.Context, fullAt time.Time) error {
+	_, err := q.db.ExecContext(ctx, deleteFullRateLimitBuckets, fullAt)
+	return err
+}
+
+const getRateLimitBucketForUpdate = `-- name: GetRateLimitBucketForUpdate :one
+SELECT key, tokens, updated_at, full_at FROM rate_limit_buckets WHERE key = $1 FOR UPDATE
+`
+
+func (q *Queries) GetRateLimitBucketForUpdate(ctx context.Context, key string) (RateLimitBucket, error) {
+	row := q.db.QueryRowContext(ctx, getRateLimitBucketForUpdate, key)
+	var i RateLimitBucket
+	err := row.Scan(
+		&i.Key,
+		&i.Tokens,
+		&i.UpdatedAt,
+		&i.FullAt,
+	)
+	return i, err
+}
+
+const updateRateLimitBucket = `-- name: UpdateRateLimitBucket :exec
+UPDATE rate_limit_buckets SET tokens = $2, updated_at = $3, full_at = $4 WHERE key = $1
+`
+
+type UpdateRateLimitBucketParams struct {
+	Key       string
+	Tokens    float64
+	UpdatedAt time.Time
+	FullAt    time.Time
+}
+
+func (q *Queries) UpdateRateLimitBucket(ctx context.Context, arg UpdateRateLimitBucketParams) error {
+	_, err := q.db.ExecContext(ctx, updateRateLimitBucket,
+		arg.Key,
+		arg.Tokens,
+		arg.UpdatedAt,
+		arg.FullAt,
+	)
+	return err
+}
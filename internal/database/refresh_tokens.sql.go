@@ -7,60 +7,199 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens(token, created_at, updated_at, expires_at ,revoked_at,user_id)
+INSERT INTO refresh_tokens(token_hash, created_at, updated_at, expires_at ,revoked_at,user_id, family_id, last_used_at, user_agent, ip_address)
 VALUES (
-	$1, NOW(),NOW(), NOW() + INTERVAL '1 hour' , NULL, $2
+	$1, NOW(),NOW(), NOW() + INTERVAL '1 hour' , NULL, $2, $3, NOW(), $4, $5
 )
-RETURNING token, created_at, updated_at, expires_at, revoked_at, user_id
+RETURNING token_hash, created_at, updated_at, expires_at, revoked_at, user_id, family_id, revoked_reason, replaced_by, last_used_at, user_agent, ip_address
 `
 
 type CreateRefreshTokenParams struct {
-	Token  string
-	UserID uuid.UUID
+	TokenHash string
+	UserID    uuid.UUID
+	FamilyID  uuid.UUID
+	UserAgent string
+	IpAddress string
 }
 
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, createRefreshToken, arg.Token, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createRefreshToken,
+		arg.TokenHash,
+		arg.UserID,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
+		&i.TokenHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ExpiresAt,
 		&i.RevokedAt,
 		&i.UserID,
+		&i.FamilyID,
+		&i.RevokedReason,
+		&i.ReplacedBy,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
 	)
 	return i, err
 }
 
-const getRefreshTokenByToken = `-- name: GetRefreshTokenByToken :one
-SELECT token, created_at, updated_at, expires_at, revoked_at, user_id from refresh_tokens where token = $1
+const getActiveSessionsByUserID = `-- name: GetActiveSessionsByUserID :many
+SELECT refresh_tokens.family_id,
+	(SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = refresh_tokens.family_id)::timestamp AS session_created_at,
+	refresh_tokens.last_used_at, refresh_tokens.expires_at, refresh_tokens.user_agent, refresh_tokens.ip_address
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1 AND refresh_tokens.revoked_at IS NULL AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at desc
+`
+
+type GetActiveSessionsByUserIDRow struct {
+	FamilyID         uuid.UUID
+	SessionCreatedAt time.Time
+	LastUsedAt       time.Time
+	ExpiresAt        time.Time
+	UserAgent        string
+	IpAddress        string
+}
+
+func (q *Queries) GetActiveSessionsByUserID(ctx context.Context, userID uuid.UUID) ([]GetActiveSessionsByUserIDRow, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveSessionsByUserID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveSessionsByUserIDRow
+	for rows.Next() {
+		var i GetActiveSessionsByUserIDRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.SessionCreatedAt,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.UserAgent,
+			&i.IpAddress,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
+SELECT token_hash, created_at, updated_at, expires_at, revoked_at, user_id, family_id, revoked_reason, replaced_by, last_used_at, user_agent, ip_address from refresh_tokens where token_hash = $1
 `
 
-func (q *Queries) GetRefreshTokenByToken(ctx context.Context, token string) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, getRefreshTokenByToken, token)
+func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenByHash, tokenHash)
 	var i RefreshToken
 	err := row.Scan(
-		&i.Token,
+		&i.TokenHash,
 		&i.CreatedAt,
 		&i.UpdatedAt,
 		&i.ExpiresAt,
 		&i.RevokedAt,
 		&i.UserID,
+		&i.FamilyID,
+		&i.RevokedReason,
+		&i.ReplacedBy,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
 	)
 	return i, err
 }
 
 const revokeAccessToToken = `-- name: RevokeAccessToToken :exec
-UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW() WHERE token = $1
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'revoked' WHERE token_hash = $1 AND revoked_at IS NULL
 `
 
-func (q *Queries) RevokeAccessToToken(ctx context.Context, token string) error {
-	_, err := q.db.ExecContext(ctx, revokeAccessToToken, token)
+func (q *Queries) RevokeAccessToToken(ctx context.Context, tokenHash string) error {
+	_, err := q.db.ExecContext(ctx, revokeAccessToToken, tokenHash)
 	return err
 }
+
+const revokeAllUserRefreshTokens = `-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'revoked'
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeAllUserRefreshTokens, userID)
+	return err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'reuse_detected'
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	return err
+}
+
+const revokeSession = `-- name: RevokeSession :execrows
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'revoked'
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeSessionParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeSession, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'rotated', replaced_by = $1::text
+WHERE token_hash = $2 AND revoked_at IS NULL
+RETURNING token_hash, created_at, updated_at, expires_at, revoked_at, user_id, family_id, revoked_reason, replaced_by, last_used_at, user_agent, ip_address
+`
+
+type RotateRefreshTokenParams struct {
+	ReplacedBy string
+	TokenHash  string
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, arg.ReplacedBy, arg.TokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.TokenHash,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.UserID,
+		&i.FamilyID,
+		&i.RevokedReason,
+		&i.ReplacedBy,
+		&i.LastUsedAt,
+		&i.UserAgent,
+		&i.IpAddress,
+	)
+	return i, err
+}
@@ -0,0 +1,179 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: reports.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const createReport = `-- name: CreateReport :one
+INSERT INTO reports(id, chirp_id, reporter_id, reason, details, status, created_at)
+VALUES (
+	gen_random_uuid(), $1, $2, $3, $4, 'open', NOW()
+)
+ON CONFLICT(chirp_id, reporter_id) DO NOTHING
+RETURNING id, chirp_id, reporter_id, reason, details, status, created_at, resolved_at, resolved_by
+`
+
+type CreateReportParams struct {
+	ChirpID    uuid.UUID
+	ReporterID uuid.UUID
+	Reason     string
+	Details    string
+}
+
+func (q *Queries) CreateReport(ctx context.Context, arg CreateReportParams) (Report, error) {
+	row := q.db.QueryRowContext(ctx, createReport,
+		arg.ChirpID,
+		arg.ReporterID,
+		arg.Reason,
+		arg.Details,
+	)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.ChirpID,
+		&i.ReporterID,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.CreatedAt,
+		&i.ResolvedAt,
+		&i.ResolvedBy,
+	)
+	return i, err
+}
+
+const dismissReport = `-- name: DismissReport :execrows
+UPDATE reports SET status = 'dismissed', resolved_at = NOW(), resolved_by = $2
+WHERE id = $1 AND status = 'open'
+`
+
+type DismissReportParams struct {
+	ID         uuid.UUID
+	ResolvedBy uuid.NullUUID
+}
+
+func (q *Queries) DismissReport(ctx context.Context, arg DismissReportParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, dismissReport, arg.ID, arg.ResolvedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getReportByID = `-- name: GetReportByID :one
+SELECT id, chirp_id, reporter_id, reason, details, status, created_at, resolved_at, resolved_by FROM reports WHERE id = $1
+`
+
+func (q *Queries) GetReportByID(ctx context.Context, id uuid.UUID) (Report, error) {
+	row := q.db.QueryRowContext(ctx, getReportByID, id)
+	var i Report
+	err := row.Scan(
+		&i.ID,
+		&i.ChirpID,
+		&i.ReporterID,
+		&i.Reason,
+		&i.Details,
+		&i.Status,
+		&i.CreatedAt,
+		&i.ResolvedAt,
+		&i.ResolvedBy,
+	)
+	return i, err
+}
+
+const getReports = `-- name: GetReports :many
+SELECT reports.id, reports.chirp_id, reports.reporter_id, reports.reason, reports.details, reports.status, reports.created_at, reports.resolved_at, reports.resolved_by, chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.search_vector, chirps.edited_at, chirps.parent_id, chirps.deleted_at, chirps.submitted_at, chirps.hidden_at FROM reports
+JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.status = $1
+	AND ($2::timestamp IS NULL
+	OR (reports.created_at, reports.id) > ($2::timestamp, $3::uuid))
+ORDER BY reports.created_at asc, reports.id asc
+LIMIT $4
+`
+
+type GetReportsParams struct {
+	Status          string
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+type GetReportsRow struct {
+	Report Report
+	Chirp  Chirp
+}
+
+func (q *Queries) GetReports(ctx context.Context, arg GetReportsParams) ([]GetReportsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReports,
+		arg.Status,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.PageSize,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReportsRow
+	for rows.Next() {
+		var i GetReportsRow
+		if err := rows.Scan(
+			&i.Report.ID,
+			&i.Report.ChirpID,
+			&i.Report.ReporterID,
+			&i.Report.Reason,
+			&i.Report.Details,
+			&i.Report.Status,
+			&i.Report.CreatedAt,
+			&i.Report.ResolvedAt,
+			&i.Report.ResolvedBy,
+			&i.Chirp.ID,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.Body,
+			&i.Chirp.UserID,
+			&i.Chirp.SearchVector,
+			&i.Chirp.EditedAt,
+			&i.Chirp.ParentID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.SubmittedAt,
+			&i.Chirp.HiddenAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const resolveChirpReports = `-- name: ResolveChirpReports :execrows
+UPDATE reports SET status = 'resolved', resolved_at = NOW(), resolved_by = $2
+WHERE chirp_id = $1 AND status = 'open'
+`
+
+type ResolveChirpReportsParams struct {
+	ChirpID    uuid.UUID
+	ResolvedBy uuid.NullUUID
+}
+
+func (q *Queries) ResolveChirpReports(ctx context.Context, arg ResolveChirpReportsParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resolveChirpReports, arg.ChirpID, arg.ResolvedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,111 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: subscriptions.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/google/uuid"
+)
+
+const activateSubscription = `-- name: ActivateSubscription :exec
+INSERT INTO subscriptions(user_id, plan, status, started_at, expires_at, ended_at, updated_at)
+VALUES (
+	$1, 'red', 'active', NOW(), COALESCE($2::timestamp, NOW() + INTERVAL '30 days'), NULL, NOW()
+)
+ON CONFLICT(user_id) DO UPDATE SET
+	status = 'active',
+	started_at = CASE WHEN subscriptions.status = 'active' THEN subscriptions.started_at ELSE NOW() END,
+	expires_at = EXCLUDED.expires_at,
+	ended_at = NULL,
+	updated_at = NOW()
+`
+
+type ActivateSubscriptionParams struct {
+	UserID    uuid.UUID
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) ActivateSubscription(ctx context.Context, arg ActivateSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, activateSubscription, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
+const endSubscription = `-- name: EndSubscription :exec
+UPDATE subscriptions SET status = $2, ended_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND status = 'active'
+`
+
+type EndSubscriptionParams struct {
+	UserID uuid.UUID
+	Status string
+}
+
+func (q *Queries) EndSubscription(ctx context.Context, arg EndSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, endSubscription, arg.UserID, arg.Status)
+	return err
+}
+
+const expireSubscriptions = `-- name: ExpireSubscriptions :execrows
+WITH expired AS (
+	UPDATE subscriptions SET status = 'expired', ended_at = expires_at, updated_at = NOW()
+	WHERE status = 'active' AND expires_at <= NOW()
+	RETURNING user_id
+)
+UPDATE users SET updated_at = NOW(), is_chirpy_red = false
+WHERE id IN (SELECT user_id FROM expired)
+`
+
+func (q *Queries) ExpireSubscriptions(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, expireSubscriptions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const getSubscriptionByUserID = `-- name: GetSubscriptionByUserID :one
+SELECT user_id, plan, status, started_at, expires_at, ended_at, updated_at FROM subscriptions WHERE user_id = $1
+`
+
+func (q *Queries) GetSubscriptionByUserID(ctx context.Context, userID uuid.UUID) (Subscription, error) {
+	row := q.db.QueryRowContext(ctx, getSubscriptionByUserID, userID)
+	var i Subscription
+	err := row.Scan(
+		&i.UserID,
+		&i.Plan,
+		&i.Status,
+		&i.StartedAt,
+		&i.ExpiresAt,
+		&i.EndedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const renewSubscription = `-- name: RenewSubscription :exec
+INSERT INTO subscriptions(user_id, plan, status, started_at, expires_at, ended_at, updated_at)
+VALUES (
+	$1, 'red', 'active', NOW(), COALESCE($2::timestamp, NOW() + INTERVAL '30 days'), NULL, NOW()
+)
+ON CONFLICT(user_id) DO UPDATE SET
+	status = 'active',
+	started_at = CASE WHEN subscriptions.status = 'active' THEN subscriptions.started_at ELSE NOW() END,
+	expires_at = COALESCE($2::timestamp, GREATEST(subscriptions.expires_at, NOW()) + INTERVAL '30 days'),
+	ended_at = NULL,
+	updated_at = NOW()
+`
+
+type RenewSubscriptionParams struct {
+	UserID    uuid.UUID
+	ExpiresAt sql.NullTime
+}
+
+func (q *Queries) RenewSubscription(ctx context.Context, arg RenewSubscriptionParams) error {
+	_, err := q.db.ExecContext(ctx, renewSubscription, arg.UserID, arg.ExpiresAt)
+	return err
+}
@@ -0,0 +1,122 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.30.0
+// source: webhook_events.sql
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+const createWebhookEvent = `-- name: CreateWebhookEvent :exec
+INSERT INTO webhook_events(id, event_id, event, payload, received_at, processed_at, attempts, last_error)
+VALUES (
+	gen_random_uuid(), $1, $2, $3, NOW(), NULL, 0, NULL
+)
+ON CONFLICT(event_id) DO NOTHING
+`
+
+type CreateWebhookEventParams struct {
+	EventID string
+	Event   string
+	Payload json.RawMessage
+}
+
+func (q *Queries) CreateWebhookEvent(ctx context.Context, arg CreateWebhookEventParams) error {
+	_, err := q.db.ExecContext(ctx, createWebhookEvent, arg.EventID, arg.Event, arg.Payload)
+	return err
+}
+
+const getWebhookEventForUpdate = `-- name: GetWebhookEventForUpdate :one
+SELECT id, event_id, event, payload, received_at, processed_at, attempts, last_error FROM webhook_events WHERE event_id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetWebhookEventForUpdate(ctx context.Context, eventID string) (WebhookEvent, error) {
+	row := q.db.QueryRowContext(ctx, getWebhookEventForUpdate, eventID)
+	var i WebhookEvent
+	err := row.Scan(
+		&i.ID,
+		&i.EventID,
+		&i.Event,
+		&i.Payload,
+		&i.ReceivedAt,
+		&i.ProcessedAt,
+		&i.Attempts,
+		&i.LastError,
+	)
+	return i, err
+}
+
+const getWebhookEvents = `-- name: GetWebhookEvents :many
+SELECT id, event_id, event, payload, received_at, processed_at, attempts, last_error FROM webhook_events
+WHERE ($1::timestamp IS NULL
+	OR (received_at, id) < ($1::timestamp, $2::uuid))
+ORDER BY received_at desc, id desc
+LIMIT $3
+`
+
+type GetWebhookEventsParams struct {
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	PageSize        int32
+}
+
+func (q *Queries) GetWebhookEvents(ctx context.Context, arg GetWebhookEventsParams) ([]WebhookEvent, error) {
+	rows, err := q.db.QueryContext(ctx, getWebhookEvents, arg.CursorCreatedAt, arg.CursorID, arg.PageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WebhookEvent
+	for rows.Next() {
+		var i WebhookEvent
+		if err := rows.Scan(
+			&i.ID,
+			&i.EventID,
+			&i.Event,
+			&i.Payload,
+			&i.ReceivedAt,
+			&i.ProcessedAt,
+			&i.Attempts,
+			&i.LastError,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markWebhookEventProcessed = `-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events SET processed_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1
+`
+
+func (q *Queries) MarkWebhookEventProcessed(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.ExecContext(ctx, markWebhookEventProcessed, id)
+	return err
+}
+
+const recordWebhookEventError = `-- name: RecordWebhookEventError :exec
+UPDATE webhook_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1
+`
+
+type RecordWebhookEventErrorParams struct {
+	ID        uuid.UUID
+	LastError sql.NullString
+}
+
+func (q *Queries) RecordWebhookEventError(ctx context.Context, arg RecordWebhookEventErrorParams) error {
+	_, err := q.db.ExecContext(ctx, recordWebhookEventError, arg.ID, arg.LastError)
+	return err
+}
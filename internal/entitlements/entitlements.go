@@ -0,0 +1,78 @@
+package entitlements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Plan is what a user may do with their account. Zero values in quotas
+// mean "unlimited".
+type Plan struct {
+	// MaxChirpLength is counted in runes, not bytes.
+	MaxChirpLength int `json:"max_chirp_length"`
+	// DailyChirpQuota limits chirps submitted in any 24 hours.
+	DailyChirpQuota int  `json:"daily_chirp_quota"`
+	CanEditChirps   bool `json:"can_edit_chirps"`
+	// MaxScheduleDays is how far ahead chirps can be scheduled. Scheduling
+	// is off when it is 0.
+	MaxScheduleDays int `json:"max_schedule_days"`
+}
+
+func (p Plan) CanScheduleChirps() bool {
+	return p.MaxScheduleDays > 0
+}
+
+type Plans struct {
+	Free Plan `json:"free"`
+	Red  Plan `json:"red"`
+}
+
+// Default returns the plans Chirpy uses without a configuration file.
+func Default() Plans {
+	return Plans{
+		Free: Plan{MaxChirpLength: 140, DailyChirpQuota: 50},
+		Red:  Plan{MaxChirpLength: 500, DailyChirpQuota: 500, CanEditChirps: true, MaxScheduleDays: 30},
+	}
+}
+
+// Load reads plans from a JSON file shaped like Plans. Fields missing from
+// the file keep their default.
+func Load(path string) (Plans, error) {
+	plans := Default()
+	if path == "" {
+		return plans, nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Plans{}, err
+	}
+	if err := json.Unmarshal(content, &plans); err != nil {
+		return Plans{}, fmt.Errorf("Error while parsing %v: %v", path, err)
+	}
+	if err := plans.validate(); err != nil {
+		return Plans{}, fmt.Errorf("Invalid plans in %v: %v", path, err)
+	}
+	return plans, nil
+}
+
+func (p Plans) validate() error {
+	for name, plan := range map[string]Plan{"free": p.Free, "red": p.Red} {
+		if plan.MaxChirpLength <= 0 {
+			return fmt.Errorf("%v plan needs a positive max_chirp_length", name)
+		}
+		if plan.DailyChirpQuota < 0 || plan.MaxScheduleDays < 0 {
+			return errors.New("Quotas can't be negative")
+		}
+	}
+	return nil
+}
+
+// For returns the plan of a user.
+func (p Plans) For(isChirpyRed bool) Plan {
+	if isChirpyRed {
+		return p.Red
+	}
+	return p.Free
+}
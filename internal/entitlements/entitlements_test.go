@@ -0,0 +1,42 @@
+package entitlements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadKeepsDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans.json")
+	os.WriteFile(path, []byte(`{"red": {"max_chirp_length": 1000}}`), 0600)
+
+	plans, err := Load(path)
+	if err != nil {
+		t.Fatalf("Plans should load, but got error: %v", err)
+	}
+	if plans.Red.MaxChirpLength != 1000 {
+		t.Errorf("Configured limit should be used, got %d", plans.Red.MaxChirpLength)
+	}
+	if plans.Red.DailyChirpQuota != Default().Red.DailyChirpQuota || plans.Free != Default().Free {
+		t.Errorf("Limits missing from the file should keep their defaults, got %+v", plans)
+	}
+}
+
+func TestLoadRejectsInvalidPlans(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plans.json")
+	os.WriteFile(path, []byte(`{"free": {"max_chirp_length": 0}}`), 0600)
+
+	if _, err := Load(path); err == nil {
+		t.Errorf("Plan without a chirp length should be rejected")
+	}
+}
+
+func TestFor(t *testing.T) {
+	plans := Default()
+	if plans.For(true) != plans.Red || plans.For(false) != plans.Free {
+		t.Errorf("Red users should get the red plan, everyone else the free plan")
+	}
+	if plans.Free.CanScheduleChirps() || !plans.Red.CanScheduleChirps() {
+		t.Errorf("By default only Red users can schedule chirps")
+	}
+}
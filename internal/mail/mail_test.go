@@ -0,0 +1,50 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer(t *testing.T) {
+	out := bytes.Buffer{}
+	mailer := &LogMailer{Out: &out}
+
+	err := mailer.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "Body text"})
+	if err != nil {
+		t.Fatalf("LogMailer should send, but produces error: %v", err)
+	}
+	for _, expected := range []string{"To: user@example.com", "Subject: Hello", "Body text"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Logged mail should contain %q, got: %v", expected, out.String())
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := FileMailer{Dir: dir}
+
+	if err := mailer.Send(context.Background(), Message{To: "user@example.com", Subject: "Hello", Body: "Body text"}); err != nil {
+		t.Fatalf("FileMailer should send, but produces error: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("FileMailer should write one .eml file, found: %v", files)
+	}
+	content, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(content), "Body text") {
+		t.Errorf("Mail file should contain the body, got: %v", string(content))
+	}
+}
+
+func TestHeaderInjectionIsRejected(t *testing.T) {
+	mailer := &LogMailer{Out: &bytes.Buffer{}}
+	err := mailer.Send(context.Background(), Message{To: "user@example.com\r\nBcc: victim@example.com", Subject: "Hello"})
+	if err == nil {
+		t.Errorf("Recipient with a line break should be rejected")
+	}
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps buckets in this process. Every instance counts on its
+// own, so use PostgresStore when running more than one.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	bucket
+	fullAt time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: map[string]memoryBucket{}}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for bucketKey, stored := range s.buckets {
+			if !stored.fullAt.After(now) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	bucketKey := policy.bucketKey(key)
+	stored, ok := s.buckets[bucketKey]
+	if !ok {
+		stored.bucket = policy.newBucket(now)
+	}
+	updated, result := policy.take(stored.bucket, now)
+	s.buckets[bucketKey] = memoryBucket{bucket: updated, fullAt: now.Add(result.Reset)}
+	return result, nil
+}
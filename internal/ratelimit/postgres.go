@@ -0,0 +1,62 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/widua/go-http-server/internal/database"
+)
+
+// PostgresStore keeps buckets in the rate_limit_buckets table, so every
+// instance behind a load balancer shares the same limits.
+type PostgresStore struct {
+	DB        *database.DatabaseConfig
+	lastSweep atomic.Int64
+}
+
+func NewPostgresStore(db *database.DatabaseConfig) *PostgresStore {
+	return &PostgresStore{DB: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	// TIMESTAMP columns drop the zone, so times are always stored in UTC.
+	now = now.UTC()
+	s.sweep(ctx, now)
+
+	tx, err := s.DB.Db_connection.BeginTx(ctx, nil)
+	if err != nil {
+		return Result{}, err
+	}
+	defer tx.Rollback()
+	queries := s.DB.Queries.WithTx(tx)
+
+	bucketKey := policy.bucketKey(key)
+	initial := policy.newBucket(now)
+	err = queries.CreateRateLimitBucket(ctx, database.CreateRateLimitBucketParams{Key: bucketKey, Tokens: initial.tokens, UpdatedAt: initial.updatedAt})
+	if err != nil {
+		return Result{}, err
+	}
+	stored, err := queries.GetRateLimitBucketForUpdate(ctx, bucketKey)
+	if err != nil {
+		return Result{}, err
+	}
+
+	updated, result := policy.take(bucket{tokens: stored.Tokens, updatedAt: stored.UpdatedAt}, now)
+	err = queries.UpdateRateLimitBucket(ctx, database.UpdateRateLimitBucketParams{Key: bucketKey, Tokens: updated.tokens, UpdatedAt: updated.updatedAt, FullAt: now.Add(result.Reset)})
+	if err != nil {
+		return Result{}, err
+	}
+	return result, tx.Commit()
+}
+
+func (s *PostgresStore) sweep(ctx context.Context, now time.Time) {
+	last := s.lastSweep.Load()
+	if now.Sub(time.Unix(0, last)) < sweepInterval || !s.lastSweep.CompareAndSwap(last, now.UnixNano()) {
+		return
+	}
+	if err := s.DB.Queries.DeleteFullRateLimitBuckets(ctx, now); err != nil {
+		log.Printf("Error while deleting full rate limit buckets: %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// sweepInterval is how often stores drop buckets that are full again. A
+// missing bucket is treated as full, so this only frees memory or rows.
+const sweepInterval = time.Minute
+
+// Policy is a token bucket: up to Limit requests can be made at once, and
+// the bucket refills evenly at Limit requests per Window. Routes that share
+// a Name share their buckets.
+type Policy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request would be allowed. It is
+	// zero when the request was allowed.
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+}
+
+// Store keeps the buckets. Take must be atomic per key, so concurrent
+// requests can't spend the same token twice.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+func (p Policy) bucketKey(key string) string {
+	return p.Name + ":" + key
+}
+
+func (p Policy) newBucket(now time.Time) bucket {
+	return bucket{tokens: float64(p.Limit), updatedAt: now}
+}
+
+// take refills b for the time passed since it was last updated and tries to
+// take a token for one request.
+func (p Policy) take(b bucket, now time.Time) (bucket, Result) {
+	perSecond := float64(p.Limit) / p.Window.Seconds()
+	elapsed := max(now.Sub(b.updatedAt).Seconds(), 0)
+	tokens := min(float64(p.Limit), b.tokens+elapsed*perSecond)
+
+	result := Result{Limit: p.Limit}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / perSecond)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = seconds((float64(p.Limit) - tokens) / perSecond)
+	return bucket{tokens: tokens, updatedAt: now}, result
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
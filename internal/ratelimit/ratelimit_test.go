@@ -0,0 +1,59 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Name: "test", Limit: 3, Window: 3 * time.Second}
+	now := time.Now()
+
+	for ix := range 3 {
+		result, _ := store.Take(context.Background(), "client", policy, now)
+		if !result.Allowed || result.Remaining != 2-ix {
+			t.Fatalf("Request %d should be allowed with %d remaining, got %+v", ix+1, 2-ix, result)
+		}
+	}
+	result, _ := store.Take(context.Background(), "client", policy, now)
+	if result.Allowed {
+		t.Fatalf("Request over the limit should be rejected")
+	}
+	if result.RetryAfter != time.Second || result.Reset != 3*time.Second {
+		t.Errorf("Rejected request should retry after 1s and reset after 3s, got %+v", result)
+	}
+
+	result, _ = store.Take(context.Background(), "client", policy, now.Add(time.Second))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("One token should be refilled after a second, got %+v", result)
+	}
+}
+
+func TestBucketsAreSeparate(t *testing.T) {
+	store := NewMemoryStore()
+	login := Policy{Name: "login", Limit: 1, Window: time.Minute}
+	chirps := Policy{Name: "chirps", Limit: 1, Window: time.Minute}
+	now := time.Now()
+
+	store.Take(context.Background(), "client", login, now)
+	if result, _ := store.Take(context.Background(), "other", login, now); !result.Allowed {
+		t.Errorf("Other clients should have their own bucket")
+	}
+	if result, _ := store.Take(context.Background(), "client", chirps, now); !result.Allowed {
+		t.Errorf("Other policies should have their own bucket")
+	}
+}
+
+func TestSweepDropsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	policy := Policy{Name: "test", Limit: 5, Window: time.Second}
+	now := time.Now()
+
+	store.Take(context.Background(), "client", policy, now)
+	store.Take(context.Background(), "other", policy, now.Add(sweepInterval))
+	if _, ok := store.buckets[policy.bucketKey("client")]; ok {
+		t.Errorf("Bucket that refilled should be swept")
+	}
+}
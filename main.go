@@ -1,14 +1,22 @@
 package main
 
 import (
+	"context"
 	"net/http"
 	"os"
+	"strings"
 	"sync/atomic"
+	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 	"github.com/widua/go-http-server/internal/api"
+	"github.com/widua/go-http-server/internal/auth"
+	"github.com/widua/go-http-server/internal/censor"
 	"github.com/widua/go-http-server/internal/database"
+	"github.com/widua/go-http-server/internal/entitlements"
+	"github.com/widua/go-http-server/internal/mail"
+	"github.com/widua/go-http-server/internal/ratelimit"
 )
 
 func main() {
@@ -17,26 +25,143 @@ func main() {
 	tokenSecret := os.Getenv("JWT_SECRET")
 	polkaKey := os.Getenv("POLKA_KEY")
 	dbconfig := database.InitializeDatabase(dbUrl)
+	jwtKeys, err := auth.LoadKeySet(tokenSecret, os.Getenv("JWT_KEYS_DIR"), os.Getenv("JWT_ACTIVE_KID"), strings.Split(os.Getenv("JWT_RETIRED_KIDS"), ","))
+	if err != nil {
+		panic("Error while loading JWT keys: " + err.Error())
+	}
+
+	platform := os.Getenv("PLATFORM")
+
+	var mailer mail.Mailer
+	if smtpHost := os.Getenv("MAIL_SMTP_HOST"); smtpHost != "" {
+		mailer = mail.SMTPMailer{Host: smtpHost, Port: os.Getenv("MAIL_SMTP_PORT"), Username: os.Getenv("MAIL_SMTP_USERNAME"), Password: os.Getenv("MAIL_SMTP_PASSWORD"), From: os.Getenv("MAIL_FROM")}
+	} else if mailDir := os.Getenv("MAIL_DIR"); mailDir != "" {
+		mailer = mail.FileMailer{Dir: mailDir}
+	} else if platform == "dev" {
+		// Mail carries live tokens, so it is only printed on dev machines.
+		mailer = &mail.LogMailer{Out: os.Stdout}
+	} else {
+		panic("No mailer configured: set MAIL_SMTP_HOST or MAIL_DIR, or PLATFORM=dev to print mail to stdout")
+	}
+
+	baseURL := os.Getenv("APP_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+
+	var rateLimiter ratelimit.Store
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		rateLimiter = ratelimit.NewMemoryStore()
+	case "postgres":
+		rateLimiter = ratelimit.NewPostgresStore(&dbconfig)
+	case "off":
+	default:
+		panic("Unknown RATE_LIMIT_STORE: " + os.Getenv("RATE_LIMIT_STORE"))
+	}
+	plans, err := entitlements.Load(os.Getenv("ENTITLEMENTS_FILE"))
+	if err != nil {
+		panic("Error while loading entitlements: " + err.Error())
+	}
+	censorWords := censor.DefaultWords
+	if wordsFile := os.Getenv("CENSOR_WORDS_FILE"); wordsFile != "" {
+		censorWords, err = censor.LoadFile(wordsFile)
+		if err != nil {
+			panic("Error while loading censored words: " + err.Error())
+		}
+	}
+	chirpCensor, err := censor.New(censorWords)
+	if err != nil {
+		panic("Error while loading censored words: " + err.Error())
+	}
+	trustedProxies, err := api.ParseTrustedProxies(os.Getenv("TRUSTED_PROXIES"))
+	if err != nil {
+		panic("Error while parsing TRUSTED_PROXIES: " + err.Error())
+	}
+	loginLimit := ratelimit.Policy{Name: "login", Limit: 10, Window: time.Minute}
+	mailLimit := ratelimit.Policy{Name: "mail", Limit: 5, Window: time.Hour}
+	chirpLimit := ratelimit.Policy{Name: "chirps", Limit: 30, Window: time.Minute}
+	reportLimit := ratelimit.Policy{Name: "reports", Limit: 20, Window: time.Hour}
 
 	serveMux := http.NewServeMux()
 	server := http.Server{
 		Handler: serveMux,
 		Addr:    ":8080",
 	}
-	config := api.ApiConfig{FileServerHits: atomic.Int32{}, JWT_Secret: tokenSecret, DB_Config: &dbconfig, POLKA_KEY: polkaKey}
+	config := api.ApiConfig{FileServerHits: atomic.Int32{}, JWT_Keys: jwtKeys, DB_Config: &dbconfig, POLKA_KEY: polkaKey, Mailer: mailer, BaseURL: strings.TrimSuffix(baseURL, "/"), Platform: platform, RequireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true", RateLimiter: rateLimiter, Plans: plans, Censor: chirpCensor, TrustedProxies: trustedProxies}
+	limited := func(policy ratelimit.Policy, handler http.Handler) http.Handler {
+		return config.RateLimitMiddleware(policy, handler)
+	}
+	authed := func(handler http.HandlerFunc) http.Handler {
+		return config.RequireAuth(handler)
+	}
+	admin := func(handler http.HandlerFunc) http.Handler {
+		return config.RequireRole(auth.RoleAdmin, handler)
+	}
+	moderator := func(handler http.HandlerFunc) http.Handler {
+		return config.RequireRole(auth.RoleModerator, handler)
+	}
+	optionallyAuthed := func(handler http.HandlerFunc) http.Handler {
+		return config.OptionalAuth(handler)
+	}
 	serveMux.Handle("/app/", config.MetricsMiddleware(api.HandleFileserver()))
-	serveMux.HandleFunc("POST /admin/reset", config.HandleReset)
+	serveMux.Handle("POST /admin/reset", admin(config.HandleReset))
+	serveMux.Handle("POST /admin/users/{userID}/unlock", admin(config.HandleUnlockUser))
+	serveMux.Handle("PUT /admin/users/{userID}/role", admin(config.HandleSetUserRole))
+	serveMux.Handle("GET /admin/webhooks/events", admin(config.HandleGetWebhookEvents))
+	serveMux.Handle("POST /admin/webhooks/events/{eventID}/replay", admin(config.HandleReplayWebhookEvent))
+	serveMux.Handle("GET /admin/censor/words", admin(config.HandleGetCensoredWords))
+	serveMux.Handle("POST /admin/censor/words", admin(config.HandleCreateCensoredWord))
+	serveMux.Handle("DELETE /admin/censor/words/{wordID}", admin(config.HandleDeleteCensoredWord))
 	serveMux.HandleFunc("GET /api/healthz", config.HandleHealthz)
-	serveMux.HandleFunc("GET /admin/metrics", config.HandleMetrics)
-	serveMux.HandleFunc("POST /api/users", config.HandleCreateUser)
-	serveMux.HandleFunc("POST /api/chirps", config.HandleCreateChirp)
-	serveMux.HandleFunc("GET /api/chirps", config.HandleGetChirps)
-	serveMux.HandleFunc("GET /api/chirps/{chirpID}", config.HandleGetChirp)
-	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", config.HandleDeleteChirp)
-	serveMux.HandleFunc("POST /api/login", config.HandleLogin)
+	serveMux.HandleFunc("GET /.well-known/jwks.json", config.HandleJWKS)
+	serveMux.Handle("GET /admin/metrics", admin(config.HandleMetrics))
+	serveMux.Handle("POST /api/users", limited(mailLimit, http.HandlerFunc(config.HandleCreateUser)))
+	serveMux.HandleFunc("GET /api/users/verify", config.HandleVerifyEmail)
+	serveMux.Handle("POST /api/users/verify/resend", limited(mailLimit, authed(config.HandleResendEmailVerification)))
+	serveMux.Handle("POST /api/chirps", limited(chirpLimit, authed(config.HandleCreateChirp)))
+	serveMux.Handle("GET /api/chirps", optionallyAuthed(config.HandleGetChirps))
+	serveMux.Handle("GET /api/chirps/search", optionallyAuthed(config.HandleSearchChirps))
+	serveMux.Handle("GET /api/chirps/scheduled", authed(config.HandleGetScheduledChirps))
+	serveMux.Handle("GET /api/chirps/{chirpID}", optionallyAuthed(config.HandleGetChirp))
+	serveMux.Handle("PUT /api/chirps/{chirpID}", authed(config.HandleUpdateChirp))
+	serveMux.Handle("DELETE /api/chirps/{chirpID}", authed(config.HandleDeleteChirp))
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}/revisions", config.HandleGetChirpRevisions)
+	serveMux.Handle("GET /api/chirps/{chirpID}/thread", optionallyAuthed(config.HandleGetChirpThread))
+	serveMux.Handle("POST /api/chirps/{chirpID}/reports", limited(reportLimit, authed(config.HandleReportChirp)))
+	serveMux.Handle("GET /api/moderation/reports", moderator(config.HandleGetReports))
+	serveMux.Handle("POST /api/moderation/reports/{reportID}/dismiss", moderator(config.HandleDismissReport))
+	serveMux.Handle("POST /api/moderation/chirps/{chirpID}/actions", moderator(config.HandleModerateChirp))
+	serveMux.Handle("POST /api/moderation/users/{userID}/actions", moderator(config.HandleModerateUser))
+	serveMux.Handle("GET /api/moderation/actions", moderator(config.HandleGetModerationActions))
+	serveMux.Handle("POST /api/chirps/{chirpID}/likes", authed(config.HandleLikeChirp))
+	serveMux.Handle("DELETE /api/chirps/{chirpID}/likes", authed(config.HandleUnlikeChirp))
+	serveMux.Handle("POST /api/login", limited(loginLimit, http.HandlerFunc(config.HandleLogin)))
+	serveMux.Handle("POST /api/login/mfa", limited(loginLimit, http.HandlerFunc(config.HandleLoginMFA)))
+	serveMux.Handle("POST /api/users/mfa/setup", authed(config.HandleMFASetup))
+	serveMux.Handle("POST /api/users/mfa/verify", authed(config.HandleMFAVerify))
+	serveMux.Handle("POST /api/password/forgot", limited(mailLimit, http.HandlerFunc(config.HandleForgotPassword)))
+	serveMux.Handle("POST /api/password/reset", limited(loginLimit, http.HandlerFunc(config.HandleResetPassword)))
 	serveMux.HandleFunc("POST /api/refresh", config.HandleRefreshToken)
 	serveMux.HandleFunc("POST /api/revoke", config.HandleRevokeToken)
-	serveMux.HandleFunc("PUT /api/users", config.HandleUpdateUser)
+	serveMux.Handle("GET /api/sessions", authed(config.HandleGetSessions))
+	serveMux.Handle("DELETE /api/sessions", authed(config.HandleDeleteAllSessions))
+	serveMux.Handle("DELETE /api/sessions/{sessionID}", authed(config.HandleDeleteSession))
+	serveMux.Handle("PUT /api/users", authed(config.HandleUpdateUser))
+	serveMux.Handle("GET /api/users/me/subscription", authed(config.HandleGetSubscription))
+	serveMux.Handle("PUT /api/users/me/profile", authed(config.HandleUpdateProfile))
+	serveMux.HandleFunc("GET /api/users/{handleOrID}", config.HandleGetProfile)
+	serveMux.Handle("POST /api/users/{userID}/follow", authed(config.HandleFollowUser))
+	serveMux.Handle("DELETE /api/users/{userID}/follow", authed(config.HandleUnfollowUser))
+	serveMux.Handle("POST /api/users/{userID}/block", authed(config.HandleBlockUser))
+	serveMux.Handle("DELETE /api/users/{userID}/block", authed(config.HandleUnblockUser))
+	serveMux.Handle("POST /api/users/{userID}/mute", authed(config.HandleMuteUser))
+	serveMux.Handle("DELETE /api/users/{userID}/mute", authed(config.HandleUnmuteUser))
+	serveMux.HandleFunc("GET /api/users/{userID}/followers", config.HandleGetFollowers)
+	serveMux.HandleFunc("GET /api/users/{userID}/following", config.HandleGetFollowing)
+	serveMux.Handle("GET /api/timeline", authed(config.HandleGetTimeline))
 	serveMux.HandleFunc("POST /api/polka/webhooks", config.HandlePolkaWebhooks)
+	go config.RunSubscriptionSweeper(context.Background(), time.Minute)
+	go config.RunCensorRefresher(context.Background(), time.Minute)
 	server.ListenAndServe()
 }
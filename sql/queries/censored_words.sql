@@ -0,0 +1,13 @@
+-- name: CreateCensoredWord :one
+INSERT INTO censored_words(id, pattern, strategy, replacement, created_at)
+VALUES (
+	gen_random_uuid(), $1, $2, $3, NOW()
+)
+ON CONFLICT(pattern) DO NOTHING
+RETURNING *;
+
+-- name: GetCensoredWords :many
+SELECT * FROM censored_words ORDER BY created_at, id;
+
+-- name: DeleteCensoredWord :execrows
+DELETE FROM censored_words WHERE id = $1;
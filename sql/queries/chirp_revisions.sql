@@ -0,0 +1,9 @@
+-- name: CreateChirpRevision :exec
+INSERT INTO chirp_revisions(id, created_at, replaced_at, body, chirp_id)
+SELECT gen_random_uuid(), chirps.updated_at, NOW(), chirps.body, chirps.id FROM chirps WHERE chirps.id = $1;
+
+-- name: GetChirpRevisions :many
+SELECT * FROM chirp_revisions WHERE chirp_id = $1 ORDER BY replaced_at desc;
+
+-- name: DeleteChirpRevisions :exec
+DELETE FROM chirp_revisions WHERE chirp_id = $1;
@@ -1,21 +1,197 @@
 -- name: CreateChirp :one
-INSERT INTO chirps(id, created_at, updated_at, body,user_id)
+INSERT INTO chirps(id, created_at, updated_at, submitted_at, body, user_id, parent_id)
 VALUES (
-	gen_random_uuid(), NOW(),NOW(), $1,$2
+	gen_random_uuid(), COALESCE(sqlc.narg('publish_at')::timestamptz, NOW()), COALESCE(sqlc.narg('publish_at')::timestamptz, NOW()), NOW(),
+	sqlc.arg('body'), sqlc.arg('user_id'), sqlc.narg('parent_id')
 )
 RETURNING *;
 
 -- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at asc;
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND (sqlc.narg('viewer_id')::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg('viewer_id')::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg('viewer_id')::uuid))
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at asc, id asc
+LIMIT sqlc.arg('page_size');
+
+-- name: GetAllChirpsDesc :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND (sqlc.narg('viewer_id')::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg('viewer_id')::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg('viewer_id')::uuid))
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at desc, id desc
+LIMIT sqlc.arg('page_size');
+
+-- name: SearchChirps :many
+WITH matches AS (
+	SELECT chirps.*, ts_rank(chirps.search_vector, query)::float8 AS rank
+	FROM chirps, websearch_to_tsquery('english', sqlc.arg('query')) query
+	WHERE chirps.search_vector @@ query
+		AND chirps.deleted_at IS NULL
+		AND chirps.created_at <= NOW()
+		AND chirps.hidden_at IS NULL
+		AND (sqlc.narg('viewer_id')::uuid IS NULL OR chirps.user_id NOT IN (
+			SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg('viewer_id')::uuid
+			UNION ALL
+			SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg('viewer_id')::uuid))
+		AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+		AND (sqlc.narg('created_after')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('created_after')::timestamp)
+		AND (sqlc.narg('created_before')::timestamp IS NULL OR chirps.created_at < sqlc.narg('created_before')::timestamp)
+)
+SELECT matches.id, matches.created_at, matches.updated_at, matches.body, matches.user_id, matches.edited_at, matches.parent_id, matches.rank,
+	ts_headline('english', matches.body, websearch_to_tsquery('english', sqlc.arg('query')), 'StartSel=' || chr(1) || ', StopSel=' || chr(2) || ', MaxFragments=2')::text AS snippet
+FROM matches
+WHERE sqlc.narg('cursor_rank')::float8 IS NULL
+	OR (matches.rank, matches.created_at, matches.id) < (sqlc.narg('cursor_rank')::float8, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid)
+ORDER BY matches.rank desc, matches.created_at desc, matches.id desc
+LIMIT sqlc.arg('page_size');
 
 -- name: GetChirpsByUserID :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at asc;
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+	AND deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND (sqlc.narg('viewer_id')::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg('viewer_id')::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg('viewer_id')::uuid))
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at asc, id asc
+LIMIT sqlc.arg('page_size');
+
+-- name: GetChirpsByUserIDDesc :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+	AND deleted_at IS NULL
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND (sqlc.narg('viewer_id')::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg('viewer_id')::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg('viewer_id')::uuid))
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at desc, id desc
+LIMIT sqlc.arg('page_size');
+
+-- name: GetTimeline :many
+SELECT chirps.* FROM chirps
+JOIN follows ON follows.followed_id = chirps.user_id
+WHERE follows.follower_id = sqlc.arg('user_id')
+	AND chirps.deleted_at IS NULL
+	AND chirps.created_at <= NOW()
+	AND chirps.hidden_at IS NULL
+	AND chirps.user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.arg('user_id')
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = sqlc.arg('user_id'))
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at desc, chirps.id desc
+LIMIT sqlc.arg('page_size');
 
 -- name: GetChirpByID :one
 SELECT * FROM chirps WHERE id = $1;
 
+-- name: GetVisibleChirpByID :one
+SELECT * FROM chirps WHERE id = $1 AND created_at <= NOW() AND hidden_at IS NULL;
+
+-- name: GetScheduledChirps :many
+SELECT * FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+	AND created_at > NOW()
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at asc, id asc
+LIMIT sqlc.arg('page_size');
+
+-- name: CountChirpsSubmittedLastDay :one
+SELECT count(*) FROM chirps WHERE user_id = $1 AND submitted_at > NOW() - interval '24 hours';
+
+-- name: GetChirpByIDForUpdate :one
+SELECT * FROM chirps WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps SET updated_at = NOW(), edited_at = NOW(), body = $1 WHERE id = $2
+RETURNING *;
+
 -- name: ResetChirps :exec
 DELETE FROM chirps;
 
 -- name: DeleteChirpByID :exec
 DELETE FROM chirps where id = $1;
+
+-- name: TombstoneChirp :exec
+UPDATE chirps SET updated_at = NOW(), deleted_at = NOW(), body = '' WHERE id = $1;
+
+-- name: CountChirpReplies :one
+SELECT count(*) FROM chirps WHERE parent_id = $1;
+
+-- name: CountVisibleChirpReplies :one
+SELECT count(*) FROM chirps
+WHERE parent_id = sqlc.arg('parent_id')
+	AND created_at <= NOW()
+	AND hidden_at IS NULL
+	AND (sqlc.narg('viewer_id')::uuid IS NULL OR user_id NOT IN (
+		SELECT blocked_id FROM blocks WHERE blocker_id = sqlc.narg('viewer_id')::uuid
+		UNION ALL
+		SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg('viewer_id')::uuid));
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+	SELECT parent.*, 1 AS depth FROM chirps parent
+	WHERE parent.id = (SELECT child.parent_id FROM chirps child WHERE child.id = sqlc.arg('chirp_id'))
+	UNION ALL
+	SELECT parent.*, ancestors.depth + 1 FROM chirps parent, ancestors
+	WHERE parent.id = ancestors.parent_id AND ancestors.depth < sqlc.arg('max_depth')::int
+)
+SELECT id, created_at, updated_at, body, user_id, edited_at, parent_id, deleted_at, hidden_at
+FROM ancestors ORDER BY depth desc;
+
+-- name: GetChirpThreadRoot :one
+WITH RECURSIVE chain AS (
+	SELECT id, parent_id FROM chirps WHERE chirps.id = sqlc.arg('chirp_id')
+	UNION ALL
+	SELECT parent.id, parent.parent_id FROM chirps parent, chain
+	WHERE parent.id = chain.parent_id
+)
+SELECT * FROM chirps WHERE id = (SELECT chain.id FROM chain WHERE chain.parent_id IS NULL);
+
+-- name: GetChirpReplies :many
+WITH RECURSIVE viewer_hidden_users AS (
+	SELECT blocked_id AS user_id FROM blocks WHERE blocker_id = sqlc.narg('viewer_id')::uuid
+	UNION ALL
+	SELECT muted_id FROM mutes WHERE muter_id = sqlc.narg('viewer_id')::uuid
+), replies AS (
+	SELECT chirps.*, 1 AS depth FROM chirps
+	WHERE chirps.parent_id = sqlc.arg('chirp_id')::uuid AND chirps.created_at <= NOW() AND chirps.hidden_at IS NULL
+		AND chirps.user_id NOT IN (SELECT user_id FROM viewer_hidden_users)
+	UNION ALL
+	SELECT chirps.*, replies.depth + 1 FROM chirps, replies
+	WHERE chirps.parent_id = replies.id AND chirps.created_at <= NOW() AND chirps.hidden_at IS NULL AND replies.depth < sqlc.arg('max_depth')::int
+		AND chirps.user_id NOT IN (SELECT user_id FROM viewer_hidden_users)
+)
+SELECT replies.id, replies.created_at, replies.updated_at, replies.body, replies.user_id, replies.edited_at, replies.parent_id, replies.deleted_at,
+	(SELECT count(*) FROM chirps WHERE chirps.parent_id = replies.id AND chirps.created_at <= NOW() AND chirps.hidden_at IS NULL
+		AND chirps.user_id NOT IN (SELECT user_id FROM viewer_hidden_users)) AS reply_count
+FROM replies
+ORDER BY replies.depth, replies.created_at, replies.id
+LIMIT sqlc.arg('max_replies');
+
+-- name: SetChirpHidden :execrows
+UPDATE chirps SET hidden_at = CASE WHEN sqlc.arg('hidden')::bool THEN COALESCE(hidden_at, NOW()) END
+WHERE id = sqlc.arg('id');
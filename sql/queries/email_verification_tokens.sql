@@ -0,0 +1,10 @@
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens(token_hash, created_at, expires_at, used_at, email, user_id)
+VALUES (
+	$1, NOW(), NOW() + INTERVAL '24 hours', NULL, $2, $3
+);
+
+-- name: UseEmailVerificationToken :one
+UPDATE email_verification_tokens SET used_at = NOW()
+WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+RETURNING user_id, email;
@@ -0,0 +1,25 @@
+-- name: FollowUser :exec
+INSERT INTO follows(follower_id, followed_id, created_at)
+VALUES (
+	$1, $2, NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows WHERE follower_id = $1 AND followed_id = $2;
+
+-- name: GetFollowers :many
+SELECT follower_id AS user_id, created_at FROM follows
+WHERE followed_id = sqlc.arg('user_id')
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, follower_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at desc, follower_id desc
+LIMIT sqlc.arg('page_size');
+
+-- name: GetFollowing :many
+SELECT followed_id AS user_id, created_at FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, followed_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at desc, followed_id desc
+LIMIT sqlc.arg('page_size');
@@ -0,0 +1,22 @@
+-- name: GetLoginLockoutSeconds :one
+SELECT COALESCE(CEIL(EXTRACT(EPOCH FROM MAX(locked_until) - NOW())), 0)::int AS retry_after
+FROM login_failures
+WHERE key = ANY(sqlc.arg('keys')::text[]) AND locked_until > NOW();
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures(key, failure_count, last_failure_at, locked_until)
+VALUES ($1, 1, NOW(), NULL)
+ON CONFLICT(key) DO UPDATE SET
+	failure_count = CASE WHEN login_failures.last_failure_at < NOW() - INTERVAL '24 hours' THEN 1 ELSE login_failures.failure_count + 1 END,
+	last_failure_at = NOW()
+RETURNING failure_count;
+
+-- name: LockLogin :exec
+UPDATE login_failures SET locked_until = NOW() + sqlc.arg('lock_seconds')::int * INTERVAL '1 second'
+WHERE key = sqlc.arg('key');
+
+-- name: ClearLoginFailures :exec
+DELETE FROM login_failures WHERE key = $1;
+
+-- name: ResetLoginFailures :exec
+DELETE FROM login_failures;
@@ -0,0 +1,14 @@
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes(id, created_at, used_at, code_hash, user_id)
+VALUES (
+	gen_random_uuid(), NOW(), NULL, $1, $2
+);
+
+-- name: GetUnusedRecoveryCodes :many
+SELECT * FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes SET used_at = NOW() WHERE id = $1 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes WHERE user_id = $1;
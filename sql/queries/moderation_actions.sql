@@ -0,0 +1,15 @@
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions(id, moderator_id, action, chirp_id, user_id, report_id, reason, suspended_until, created_at)
+VALUES (
+	gen_random_uuid(), sqlc.arg('moderator_id'), sqlc.arg('action'), sqlc.narg('chirp_id'), sqlc.narg('user_id'), sqlc.narg('report_id'), sqlc.arg('reason'), sqlc.narg('suspended_until'), NOW()
+)
+RETURNING *;
+
+-- name: GetModerationActions :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id')::uuid)
+	AND (sqlc.narg('chirp_id')::uuid IS NULL OR chirp_id = sqlc.narg('chirp_id')::uuid)
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at desc, id desc
+LIMIT sqlc.arg('page_size');
@@ -1,12 +1,37 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens(token, created_at, updated_at, expires_at ,revoked_at,user_id)
+INSERT INTO refresh_tokens(token_hash, created_at, updated_at, expires_at ,revoked_at,user_id, family_id, last_used_at, user_agent, ip_address)
 VALUES (
-	$1, NOW(),NOW(), NOW() + INTERVAL '1 hour' , NULL, $2
+	$1, NOW(),NOW(), NOW() + INTERVAL '1 hour' , NULL, $2, $3, NOW(), $4, $5
 )
 RETURNING *;
 
--- name: GetRefreshTokenByToken :one
-SELECT * from refresh_tokens where token = $1;
+-- name: GetRefreshTokenByHash :one
+SELECT * from refresh_tokens where token_hash = $1;
 
 -- name: RevokeAccessToToken :exec
-UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW() WHERE token = $1;
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'revoked' WHERE token_hash = $1 AND revoked_at IS NULL;
+
+-- name: RotateRefreshToken :one
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'rotated', replaced_by = sqlc.arg('replaced_by')::text
+WHERE token_hash = sqlc.arg('token_hash') AND revoked_at IS NULL
+RETURNING *;
+
+-- name: RevokeRefreshTokenFamily :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'reuse_detected'
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: GetActiveSessionsByUserID :many
+SELECT refresh_tokens.family_id,
+	(SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = refresh_tokens.family_id)::timestamp AS session_created_at,
+	refresh_tokens.last_used_at, refresh_tokens.expires_at, refresh_tokens.user_agent, refresh_tokens.ip_address
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1 AND refresh_tokens.revoked_at IS NULL AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at desc;
+
+-- name: RevokeSession :execrows
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'revoked'
+WHERE family_id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeAllUserRefreshTokens :exec
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'revoked'
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,27 @@
+-- name: CreateReport :one
+INSERT INTO reports(id, chirp_id, reporter_id, reason, details, status, created_at)
+VALUES (
+	gen_random_uuid(), $1, $2, $3, $4, 'open', NOW()
+)
+ON CONFLICT(chirp_id, reporter_id) DO NOTHING
+RETURNING *;
+
+-- name: GetReports :many
+SELECT sqlc.embed(reports), sqlc.embed(chirps) FROM reports
+JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.status = sqlc.arg('status')
+	AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (reports.created_at, reports.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY reports.created_at asc, reports.id asc
+LIMIT sqlc.arg('page_size');
+
+-- name: GetReportByID :one
+SELECT * FROM reports WHERE id = $1;
+
+-- name: ResolveChirpReports :execrows
+UPDATE reports SET status = 'resolved', resolved_at = NOW(), resolved_by = $2
+WHERE chirp_id = $1 AND status = 'open';
+
+-- name: DismissReport :execrows
+UPDATE reports SET status = 'dismissed', resolved_at = NOW(), resolved_by = $2
+WHERE id = $1 AND status = 'open';
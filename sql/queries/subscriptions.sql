@@ -0,0 +1,39 @@
+-- name: ActivateSubscription :exec
+INSERT INTO subscriptions(user_id, plan, status, started_at, expires_at, ended_at, updated_at)
+VALUES (
+	sqlc.arg('user_id'), 'red', 'active', NOW(), COALESCE(sqlc.narg('expires_at')::timestamp, NOW() + INTERVAL '30 days'), NULL, NOW()
+)
+ON CONFLICT(user_id) DO UPDATE SET
+	status = 'active',
+	started_at = CASE WHEN subscriptions.status = 'active' THEN subscriptions.started_at ELSE NOW() END,
+	expires_at = EXCLUDED.expires_at,
+	ended_at = NULL,
+	updated_at = NOW();
+
+-- name: RenewSubscription :exec
+INSERT INTO subscriptions(user_id, plan, status, started_at, expires_at, ended_at, updated_at)
+VALUES (
+	sqlc.arg('user_id'), 'red', 'active', NOW(), COALESCE(sqlc.narg('expires_at')::timestamp, NOW() + INTERVAL '30 days'), NULL, NOW()
+)
+ON CONFLICT(user_id) DO UPDATE SET
+	status = 'active',
+	started_at = CASE WHEN subscriptions.status = 'active' THEN subscriptions.started_at ELSE NOW() END,
+	expires_at = COALESCE(sqlc.narg('expires_at')::timestamp, GREATEST(subscriptions.expires_at, NOW()) + INTERVAL '30 days'),
+	ended_at = NULL,
+	updated_at = NOW();
+
+-- name: EndSubscription :exec
+UPDATE subscriptions SET status = $2, ended_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND status = 'active';
+
+-- name: GetSubscriptionByUserID :one
+SELECT * FROM subscriptions WHERE user_id = $1;
+
+-- name: ExpireSubscriptions :execrows
+WITH expired AS (
+	UPDATE subscriptions SET status = 'expired', ended_at = expires_at, updated_at = NOW()
+	WHERE status = 'active' AND expires_at <= NOW()
+	RETURNING user_id
+)
+UPDATE users SET updated_at = NOW(), is_chirpy_red = false
+WHERE id IN (SELECT user_id FROM expired);
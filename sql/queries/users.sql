@@ -1,7 +1,8 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email,hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle)
 VALUES (
-	gen_random_uuid(), NOW(),NOW(), $1,$2
+	gen_random_uuid(), NOW(), NOW(), sqlc.arg('email'), sqlc.arg('hashed_password'),
+	COALESCE(sqlc.narg('handle')::text, 'user_' || substr(md5(random()::text), 1, 12))
 )
 RETURNING *;
 
@@ -14,8 +15,56 @@ SELECT * FROM users where email = $1;
 -- name: GetUserByID :one
 SELECT * FROM users where id = $1;
 
+-- name: GetUserByHandle :one
+SELECT * FROM users where lower(handle) = lower($1);
+
+-- name: UpdateUserProfile :one
+UPDATE users SET updated_at = NOW(), handle = $1, display_name = $2, bio = $3, avatar_url = $4
+where id = $5
+RETURNING *;
+
+-- name: GetUserProfileStats :one
+SELECT
+	(SELECT count(*) FROM chirps WHERE chirps.user_id = $1 AND deleted_at IS NULL AND created_at <= NOW() AND hidden_at IS NULL) AS chirp_count,
+	(SELECT count(*) FROM follows WHERE followed_id = $1) AS follower_count,
+	(SELECT count(*) FROM follows WHERE follower_id = $1) AS following_count;
+
+-- name: GetChirpAuthors :many
+SELECT id, handle, display_name FROM users WHERE id = ANY(sqlc.arg('user_ids')::uuid[]);
+
 -- name: UpdateUser :exec
-UPDATE users SET updated_at = NOW(), email = $1, hashed_password = $2 where id = $3;
+UPDATE users SET updated_at = NOW(), email = $1, hashed_password = $2,
+	email_verified_at = CASE WHEN email = $1 THEN email_verified_at ELSE NULL END
+where id = $3;
+
+-- name: SetUserChirpyRed :execrows
+UPDATE users SET updated_at = NOW(), is_chirpy_red = $1 where id = $2;
+
+-- name: SetUserTOTPSecret :exec
+UPDATE users SET updated_at = NOW(), totp_secret = $1, totp_enabled_at = NULL, totp_last_used_step = 0 where id = $2;
+
+-- name: EnableUserTOTP :exec
+UPDATE users SET updated_at = NOW(), totp_enabled_at = NOW(), totp_last_used_step = $1 where id = $2;
+
+-- name: UseUserTOTPStep :execrows
+UPDATE users SET totp_last_used_step = $1 where id = $2 AND totp_last_used_step < $1;
+
+-- name: UpdateUserPassword :exec
+UPDATE users SET updated_at = NOW(), hashed_password = $1 where id = $2;
+
+-- name: MarkUserEmailVerified :execrows
+UPDATE users SET updated_at = NOW(), email_verified_at = NOW() where id = $1 AND email = $2;
+
+-- name: SetUserRole :execrows
+UPDATE users SET updated_at = NOW(), role = $1 where id = $2;
+
+-- name: PromoteIfNoAdmin :one
+UPDATE users SET updated_at = NOW(), role = 'admin'
+WHERE users.id = $1 AND NOT EXISTS (SELECT 1 FROM users admins WHERE admins.role = 'admin')
+RETURNING *;
+
+-- name: SetUserSuspendedUntil :execrows
+UPDATE users SET updated_at = NOW(), suspended_until = $1 where id = $2;
 
--- name: UpgradeUserToRed :exec
-UPDATE users SET updated_at = NOW(), is_chirpy_red = true where id = $1;
+-- name: LockUser :exec
+SELECT id FROM users WHERE id = $1 FOR UPDATE;
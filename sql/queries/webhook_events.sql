@@ -0,0 +1,22 @@
+-- name: CreateWebhookEvent :exec
+INSERT INTO webhook_events(id, event_id, event, payload, received_at, processed_at, attempts, last_error)
+VALUES (
+	gen_random_uuid(), $1, $2, $3, NOW(), NULL, 0, NULL
+)
+ON CONFLICT(event_id) DO NOTHING;
+
+-- name: GetWebhookEventForUpdate :one
+SELECT * FROM webhook_events WHERE event_id = $1 FOR UPDATE;
+
+-- name: MarkWebhookEventProcessed :exec
+UPDATE webhook_events SET processed_at = NOW(), attempts = attempts + 1, last_error = NULL WHERE id = $1;
+
+-- name: RecordWebhookEventError :exec
+UPDATE webhook_events SET attempts = attempts + 1, last_error = $2 WHERE id = $1;
+
+-- name: GetWebhookEvents :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (received_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY received_at desc, id desc
+LIMIT sqlc.arg('page_size');
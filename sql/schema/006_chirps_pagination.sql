@@ -0,0 +1,7 @@
+-- +goose Up
+CREATE INDEX idx_chirps_created_at_id ON chirps(created_at, id);
+CREATE INDEX idx_chirps_user_id_created_at_id ON chirps(user_id, created_at, id);
+
+-- +goose Down
+DROP INDEX idx_chirps_user_id_created_at_id;
+DROP INDEX idx_chirps_created_at_id;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE chirps ADD search_vector tsvector GENERATED ALWAYS AS (to_tsvector('english', body)) STORED;
+CREATE INDEX idx_chirps_search_vector ON chirps USING GIN(search_vector);
+
+-- +goose Down
+DROP INDEX idx_chirps_search_vector;
+ALTER TABLE chirps DROP COLUMN search_vector;
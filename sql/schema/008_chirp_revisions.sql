@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE chirps ADD edited_at TIMESTAMP;
+CREATE TABLE chirp_revisions(
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+replaced_at TIMESTAMP NOT NULL,
+body TEXT NOT NULL,
+chirp_id UUID NOT NULL,
+CONSTRAINT fk_chirpid
+	FOREIGN KEY(chirp_id)
+	REFERENCES chirps(id)
+	ON DELETE CASCADE
+);
+CREATE INDEX idx_chirp_revisions_chirp_id ON chirp_revisions(chirp_id, replaced_at);
+
+-- +goose Down
+DROP TABLE chirp_revisions;
+ALTER TABLE chirps DROP COLUMN edited_at;
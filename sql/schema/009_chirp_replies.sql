@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE chirps ADD parent_id UUID;
+ALTER TABLE chirps ADD deleted_at TIMESTAMP;
+ALTER TABLE chirps ADD CONSTRAINT fk_parentid
+	FOREIGN KEY(parent_id)
+	REFERENCES chirps(id)
+	ON DELETE SET NULL;
+CREATE INDEX idx_chirps_parent_id ON chirps(parent_id, created_at);
+
+-- +goose Down
+DROP INDEX idx_chirps_parent_id;
+ALTER TABLE chirps DROP CONSTRAINT fk_parentid;
+ALTER TABLE chirps DROP COLUMN deleted_at;
+ALTER TABLE chirps DROP COLUMN parent_id;
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE follows(
+follower_id UUID NOT NULL,
+followed_id UUID NOT NULL,
+created_at TIMESTAMP NOT NULL,
+PRIMARY KEY(follower_id, followed_id),
+CONSTRAINT fk_followerid
+	FOREIGN KEY(follower_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT fk_followedid
+	FOREIGN KEY(followed_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT chk_follows_not_self CHECK (follower_id <> followed_id)
+);
+CREATE INDEX idx_follows_follower_id_created_at ON follows(follower_id, created_at);
+CREATE INDEX idx_follows_followed_id_created_at ON follows(followed_id, created_at);
+
+-- +goose Down
+DROP TABLE follows;
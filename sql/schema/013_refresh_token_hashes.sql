@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+UPDATE refresh_tokens SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+UPDATE refresh_tokens SET replaced_by = encode(sha256(convert_to(replaced_by, 'UTF8')), 'hex') WHERE replaced_by IS NOT NULL;
+
+-- +goose Down
+-- Digests can't be turned back into tokens, so every existing session ends.
+UPDATE refresh_tokens SET updated_at = NOW(), revoked_at = NOW(), revoked_reason = 'revoked' WHERE revoked_at IS NULL;
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
@@ -0,0 +1,22 @@
+-- +goose Up
+ALTER TABLE users ADD totp_secret TEXT;
+ALTER TABLE users ADD totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD totp_last_used_step BIGINT NOT NULL DEFAULT 0;
+CREATE TABLE mfa_recovery_codes(
+id UUID PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+used_at TIMESTAMP,
+code_hash TEXT NOT NULL,
+user_id UUID NOT NULL,
+CONSTRAINT fk_userid
+	FOREIGN KEY(user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE
+);
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+-- +goose Down
+DROP TABLE mfa_recovery_codes;
+ALTER TABLE users DROP COLUMN totp_last_used_step;
+ALTER TABLE users DROP COLUMN totp_enabled_at;
+ALTER TABLE users DROP COLUMN totp_secret;
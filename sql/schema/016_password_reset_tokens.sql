@@ -0,0 +1,16 @@
+-- +goose Up
+CREATE TABLE password_reset_tokens(
+token_hash TEXT PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+expires_at TIMESTAMP NOT NULL,
+used_at TIMESTAMP,
+user_id UUID NOT NULL,
+CONSTRAINT fk_userid
+	FOREIGN KEY(user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE
+);
+CREATE INDEX idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
+
+-- +goose Down
+DROP TABLE password_reset_tokens;
@@ -0,0 +1,21 @@
+-- +goose Up
+ALTER TABLE users ADD email_verified_at TIMESTAMP;
+-- Accounts created before verification existed keep working as before.
+UPDATE users SET email_verified_at = created_at;
+CREATE TABLE email_verification_tokens(
+token_hash TEXT PRIMARY KEY,
+created_at TIMESTAMP NOT NULL,
+expires_at TIMESTAMP NOT NULL,
+used_at TIMESTAMP,
+email TEXT NOT NULL,
+user_id UUID NOT NULL,
+CONSTRAINT fk_userid
+	FOREIGN KEY(user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE
+);
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
+
+-- +goose Down
+DROP TABLE email_verification_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
@@ -0,0 +1,10 @@
+-- +goose Up
+CREATE TABLE login_failures(
+key TEXT PRIMARY KEY,
+failure_count INTEGER NOT NULL,
+last_failure_at TIMESTAMP NOT NULL,
+locked_until TIMESTAMP
+);
+
+-- +goose Down
+DROP TABLE login_failures;
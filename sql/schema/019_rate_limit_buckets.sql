@@ -0,0 +1,11 @@
+-- +goose Up
+CREATE TABLE rate_limit_buckets(
+key TEXT PRIMARY KEY,
+tokens DOUBLE PRECISION NOT NULL,
+updated_at TIMESTAMP NOT NULL,
+full_at TIMESTAMP NOT NULL
+);
+CREATE INDEX idx_rate_limit_buckets_full_at ON rate_limit_buckets(full_at);
+
+-- +goose Down
+DROP TABLE rate_limit_buckets;
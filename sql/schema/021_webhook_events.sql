@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE webhook_events(
+id UUID PRIMARY KEY,
+event_id TEXT NOT NULL UNIQUE,
+event TEXT NOT NULL,
+payload JSONB NOT NULL,
+received_at TIMESTAMP NOT NULL,
+processed_at TIMESTAMP,
+attempts INTEGER NOT NULL DEFAULT 0,
+last_error TEXT
+);
+CREATE INDEX idx_webhook_events_received_at ON webhook_events(received_at, id);
+
+-- +goose Down
+DROP TABLE webhook_events;
@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE subscriptions(
+user_id UUID PRIMARY KEY,
+plan TEXT NOT NULL,
+status TEXT NOT NULL,
+started_at TIMESTAMP NOT NULL,
+expires_at TIMESTAMP,
+ended_at TIMESTAMP,
+updated_at TIMESTAMP NOT NULL,
+CONSTRAINT fk_userid
+	FOREIGN KEY(user_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT subscriptions_status_check CHECK (status IN ('active', 'canceled', 'expired'))
+);
+CREATE INDEX idx_subscriptions_active_expires_at ON subscriptions(expires_at) WHERE status = 'active';
+-- Upgrades from before subscriptions were tracked never expire.
+INSERT INTO subscriptions(user_id, plan, status, started_at, expires_at, ended_at, updated_at)
+SELECT id, 'red', 'active', updated_at, NULL, NULL, NOW() FROM users WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
@@ -0,0 +1,11 @@
+-- +goose Up
+-- created_at is when a chirp is published, which is in the future for
+-- scheduled chirps. submitted_at is when it was actually posted.
+ALTER TABLE chirps ADD submitted_at TIMESTAMP;
+UPDATE chirps SET submitted_at = created_at;
+ALTER TABLE chirps ALTER COLUMN submitted_at SET NOT NULL;
+CREATE INDEX idx_chirps_user_id_submitted_at ON chirps(user_id, submitted_at);
+
+-- +goose Down
+DROP INDEX idx_chirps_user_id_submitted_at;
+ALTER TABLE chirps DROP COLUMN submitted_at;
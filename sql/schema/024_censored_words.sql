@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE censored_words(
+id UUID PRIMARY KEY,
+pattern TEXT NOT NULL UNIQUE,
+strategy TEXT NOT NULL,
+replacement TEXT NOT NULL DEFAULT '',
+created_at TIMESTAMP NOT NULL,
+CONSTRAINT censored_words_strategy_check CHECK (strategy IN ('mask', 'stars', 'first_letter', 'replace'))
+);
+
+-- +goose Down
+DROP TABLE censored_words;
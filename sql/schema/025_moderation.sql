@@ -0,0 +1,53 @@
+-- +goose Up
+ALTER TABLE chirps ADD hidden_at TIMESTAMP;
+ALTER TABLE users ADD suspended_until TIMESTAMP;
+
+CREATE TABLE reports(
+id UUID PRIMARY KEY,
+chirp_id UUID NOT NULL,
+reporter_id UUID NOT NULL,
+reason TEXT NOT NULL,
+details TEXT NOT NULL DEFAULT '',
+status TEXT NOT NULL DEFAULT 'open',
+created_at TIMESTAMP NOT NULL,
+resolved_at TIMESTAMP,
+resolved_by UUID,
+CONSTRAINT fk_chirpid
+	FOREIGN KEY(chirp_id)
+	REFERENCES chirps(id)
+	ON DELETE CASCADE,
+CONSTRAINT fk_reporterid
+	FOREIGN KEY(reporter_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT fk_resolvedby
+	FOREIGN KEY(resolved_by)
+	REFERENCES users(id)
+	ON DELETE SET NULL,
+CONSTRAINT reports_reason_check CHECK (reason IN ('spam', 'harassment', 'hate', 'violence', 'misinformation', 'other')),
+CONSTRAINT reports_status_check CHECK (status IN ('open', 'resolved', 'dismissed')),
+UNIQUE(chirp_id, reporter_id)
+);
+CREATE INDEX idx_reports_status_created_at ON reports(status, created_at, id);
+
+-- The audit trail outlives the chirps and users it is about, so it keeps
+-- plain IDs instead of foreign keys.
+CREATE TABLE moderation_actions(
+id UUID PRIMARY KEY,
+moderator_id UUID NOT NULL,
+action TEXT NOT NULL,
+chirp_id UUID,
+user_id UUID,
+report_id UUID,
+reason TEXT NOT NULL DEFAULT '',
+suspended_until TIMESTAMP,
+created_at TIMESTAMP NOT NULL,
+CONSTRAINT moderation_actions_action_check CHECK (action IN ('hide_chirp', 'unhide_chirp', 'delete_chirp', 'warn_user', 'suspend_user', 'unsuspend_user', 'dismiss_report'))
+);
+CREATE INDEX idx_moderation_actions_created_at ON moderation_actions(created_at, id);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+ALTER TABLE users DROP COLUMN suspended_until;
+ALTER TABLE chirps DROP COLUMN hidden_at;
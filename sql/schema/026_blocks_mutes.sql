@@ -0,0 +1,36 @@
+-- +goose Up
+CREATE TABLE blocks(
+blocker_id UUID NOT NULL,
+blocked_id UUID NOT NULL,
+created_at TIMESTAMP NOT NULL,
+PRIMARY KEY(blocker_id, blocked_id),
+CONSTRAINT fk_blockerid
+	FOREIGN KEY(blocker_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT fk_blockedid
+	FOREIGN KEY(blocked_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT chk_blocks_not_self CHECK (blocker_id <> blocked_id)
+);
+
+CREATE TABLE mutes(
+muter_id UUID NOT NULL,
+muted_id UUID NOT NULL,
+created_at TIMESTAMP NOT NULL,
+PRIMARY KEY(muter_id, muted_id),
+CONSTRAINT fk_muterid
+	FOREIGN KEY(muter_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT fk_mutedid
+	FOREIGN KEY(muted_id)
+	REFERENCES users(id)
+	ON DELETE CASCADE,
+CONSTRAINT chk_mutes_not_self CHECK (muter_id <> muted_id)
+);
+
+-- +goose Down
+DROP TABLE mutes;
+DROP TABLE blocks;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE users
+	ADD handle TEXT,
+	ADD display_name TEXT NOT NULL DEFAULT '',
+	ADD bio TEXT NOT NULL DEFAULT '',
+	ADD avatar_url TEXT NOT NULL DEFAULT '';
+-- Existing users get a generated handle they can change later.
+UPDATE users SET handle = 'user_' || substr(replace(id::text, '-', ''), 1, 12);
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+CREATE UNIQUE INDEX idx_users_handle ON users(lower(handle));
+
+-- +goose Down
+DROP INDEX idx_users_handle;
+ALTER TABLE users
+	DROP COLUMN avatar_url,
+	DROP COLUMN bio,
+	DROP COLUMN display_name,
+	DROP COLUMN handle;